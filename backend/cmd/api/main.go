@@ -5,26 +5,46 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/NickolaiP/taskApi/backend/internal/config"
 	"github.com/NickolaiP/taskApi/backend/internal/database"
 	"github.com/NickolaiP/taskApi/backend/internal/hand"
 	"github.com/NickolaiP/taskApi/backend/internal/logger"
+	"github.com/NickolaiP/taskApi/backend/internal/metrics"
+	"github.com/NickolaiP/taskApi/backend/internal/middleware"
+	"github.com/NickolaiP/taskApi/backend/internal/notify"
+	"github.com/NickolaiP/taskApi/backend/internal/worker"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 )
 
 func main() {
-	// Загружаем конфигурацию приложения
-	cfg := config.LoadConfig()
+	// Загружаем конфигурацию приложения; до ее загрузки уровень и формат логов
+	// неизвестны, поэтому ошибка записывается логгером с настройками по умолчанию
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.InitLogger(os.Stdout, "info", "json").Error("Failed to load config", "error", err)
+		return
+	}
 
 	// Инициализируем логгер для записи логов в стандартный вывод (stdout)
-	logger := logger.InitLogger(os.Stdout)
+	// с уровнем и форматом из конфигурации
+	logger := logger.InitLogger(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+
+	// Проверяем часовой пояс по умолчанию до подключения к базе данных,
+	// чтобы сервис не стартовал с некорректной конфигурацией
+	location, err := time.LoadLocation(cfg.App.DefaultTimezone)
+	if err != nil {
+		logger.Error("Invalid DEFAULT_TIMEZONE", "timezone", cfg.App.DefaultTimezone, "error", err)
+		return
+	}
 
 	// Подключаемся к базе данных PostgreSQL с использованием настроек из конфигурации
-	db, err := database.NewPostgresDB(cfg.DB)
+	db, err := database.NewPostgresDB(cfg.DB, logger)
 	if err != nil {
 		// Логируем ошибку при подключении к базе данных и выходим из программы
 		logger.Error("Failed to connect to database", "error", err)
@@ -34,58 +54,271 @@ func main() {
 	defer db.Close()
 
 	// Выполняем миграции базы данных для обновления её структуры
-	database.RunMigrations(db)
+	if err := database.RunMigrations(db, logger); err != nil {
+		logger.Error("Failed to run migrations", "error", err)
+		return
+	}
+
+	// Передаем задачи и шаблоны, созданные до появления учетных записей, их владельцу;
+	// если он еще не зарегистрирован, они будут переданы при его регистрации
+	if email := cfg.Auth.LegacyOwnerEmail; email != "" {
+		claimCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		tasks, templates, err := database.ClaimLegacyData(claimCtx, db, email)
+		cancel()
+		if err != nil {
+			logger.Error("Failed to claim legacy data", "email", email, "error", err)
+			return
+		}
+		if tasks > 0 || templates > 0 {
+			logger.Info("Claimed legacy data", "email", email, "tasks", tasks, "templates", templates)
+		}
+	}
+
+	// Общий контекст сервиса отменяется сигналом остановки; фоновые задачи
+	// по его отмене завершают начатую работу и выходят
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Запускаем фоновые задачи; перед выходом из программы дожидаемся их завершения
+	var workers sync.WaitGroup
+	runWorker := func(run func(context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(ctx)
+		}()
+	}
+	// Создание следующих повторений выполненных повторяющихся задач
+	runWorker(worker.NewRecurrenceWorker(db, logger, cfg.Worker.RecurrenceInterval).Run)
+	// Отправка наступивших напоминаний о задачах, если задан адрес для них
+	if cfg.Worker.ReminderWebhookURL != "" {
+		notifier := notify.NewWebhook(cfg.Worker.ReminderWebhookURL)
+		runWorker(worker.NewReminderWorker(db, logger, notifier, cfg.Worker.ReminderInterval).Run)
+	}
 
 	// Создаём новый маршрутизатор для обработки HTTP-запросов
 	r := mux.NewRouter()
 
-	// Инициализируем обработчик задач с подключением к базе данных и логгером
-	taskHandler := hand.NewTaskHandler(db, logger)
+	// Проверки состояния сервиса для балансировщиков и оркестраторов
+	healthHandler := hand.NewHealthHandler(db, logger)
+	// Проверка того, что процесс жив (liveness)
+	r.HandleFunc("/health", healthHandler.Health).Methods("GET", "HEAD")
+	// Проверка готовности обслуживать запросы, включая доступность базы данных (readiness)
+	r.HandleFunc("/ready", healthHandler.Ready).Methods("GET", "HEAD")
+
+	// Получение несекретных настроек сервера для клиентов
+	r.HandleFunc("/config", hand.NewConfigHandler(cfg.App, logger).GetConfig).Methods("GET")
+
+	// Метрики запросов и пула соединений для Prometheus, если они включены
+	var observers []middleware.RequestObserver
+	if cfg.Server.MetricsEnabled {
+		registry := metrics.NewRegistry(db.Stats)
+		r.Handle("/metrics", registry).Methods("GET")
+		// Маршрут запроса нужен метрикам для группировки запросов
+		r.Use(middleware.RecordRoute)
+		observers = append(observers, registry)
+	}
+
+	// Регистрация и вход пользователей доступны без аутентификации
+	userHandler := hand.NewUserHandler(db, logger, cfg.App, cfg.DB.QueryTimeout, cfg.Auth)
+	// Регистрация пользователя
+	r.HandleFunc("/users", userHandler.Register).Methods("POST")
+	// Вход пользователя и получение токена доступа
+	r.HandleFunc("/login", userHandler.Login).Methods("POST")
+
+	// Календарь задач для подписки из приложений календаря. Такие приложения
+	// не умеют передавать заголовки, поэтому принимается и токен календаря в параметре
+	// token: он дает доступ только к этому маршруту и отзывается отдельно от API-ключей
+	calendar := r.NewRoute().Subrouter()
+	calendar.Use(middleware.Authenticate(logger, cfg.DB.QueryTimeout,
+		middleware.BearerToken([]byte(cfg.Auth.JWTSecret)),
+		middleware.APIKey(db),
+		middleware.CalendarTokenParam(db, "token"),
+	))
+
+	// Остальные маршруты требуют токена доступа или API-ключа: каждый пользователь
+	// видит и изменяет только свои задачи. Публичные маршруты зарегистрированы
+	// выше, поэтому сопоставляются раньше защищенных
+	api := r.NewRoute().Subrouter()
+	api.Use(middleware.Authenticate(logger, cfg.DB.QueryTimeout,
+		middleware.BearerToken([]byte(cfg.Auth.JWTSecret)),
+		middleware.APIKey(db),
+	))
+
+	// Настраиваем маршруты для работы с API-ключами пользователя
+	// Создание API-ключа
+	api.HandleFunc("/api-keys", userHandler.CreateAPIKey).Methods("POST")
+	// Получение API-ключей пользователя
+	api.HandleFunc("/api-keys", userHandler.GetAPIKeys).Methods("GET")
+	// Отзыв API-ключа по ID
+	api.HandleFunc("/api-keys/{id:[0-9]+}", userHandler.RevokeAPIKey).Methods("DELETE")
+
+	// Настраиваем маршруты для работы с токенами подписки на календарь
+	// Создание токена календаря
+	api.HandleFunc("/calendar-tokens", userHandler.CreateCalendarToken).Methods("POST")
+	// Получение токенов календаря пользователя
+	api.HandleFunc("/calendar-tokens", userHandler.GetCalendarTokens).Methods("GET")
+	// Отзыв токена календаря по ID
+	api.HandleFunc("/calendar-tokens/{id:[0-9]+}", userHandler.RevokeCalendarToken).Methods("DELETE")
+
+	// Служебные маршруты доступны только пользователям из ADMIN_EMAILS
+	admin := api.PathPrefix("/admin").Subrouter()
+	admin.Use(middleware.RequireAdmin(logger, db, cfg.DB.QueryTimeout, cfg.Auth.AdminEmails))
+	// Состояние схемы базы данных: примененные и ожидающие миграции
+	admin.HandleFunc("/schema", hand.NewAdminHandler(db, logger, cfg.DB.QueryTimeout).GetSchema).Methods("GET")
+
+	// Инициализируем обработчик задач с подключением к базе данных, логгером,
+	// настройками приложения, таймаутом запросов к базе данных и часовым поясом по умолчанию
+	taskHandler := hand.NewTaskHandler(db, logger, cfg.App, cfg.DB.QueryTimeout, location)
+
+	// Инициализируем обработчик шаблонов задач
+	templateHandler := hand.NewTemplateHandler(db, logger, cfg.App, cfg.DB.QueryTimeout)
+
+	// Получение задач со сроком выполнения в формате iCalendar
+	calendar.HandleFunc("/tasks/calendar.ics", taskHandler.GetCalendar).Methods("GET")
 
 	// Настраиваем маршруты для работы с задачами
 	// Создание новой задачи
-	r.HandleFunc("/tasks", taskHandler.CreateTask).Methods("POST")
+	api.HandleFunc("/tasks", taskHandler.CreateTask).Methods("POST")
 	// Получение всех задач
-	r.HandleFunc("/tasks", taskHandler.GetTasks).Methods("GET")
+	api.HandleFunc("/tasks", taskHandler.GetTasks).Methods("GET")
+	// Удаление всех задач, подходящих под фильтр по статусу (например, выполненных)
+	api.HandleFunc("/tasks", taskHandler.DeleteTasks).Methods("DELETE")
+	// Массовое создание задач в одной транзакции
+	api.HandleFunc("/tasks/bulk", taskHandler.CreateTasksBulk).Methods("POST")
+	// Добавление метки всем задачам, подходящим под фильтр
+	api.HandleFunc("/tasks/tag-by-filter", taskHandler.TagTasksByFilter).Methods("POST")
+	// Поиск задач по точному совпадению заголовка
+	api.HandleFunc("/tasks/by-title", taskHandler.GetTasksByTitle).Methods("GET")
+	// Создание или обновление задачи по заголовку
+	api.HandleFunc("/tasks/by-title/{title}", taskHandler.UpsertTaskByTitle).Methods("PUT")
+	// Автодополнение заголовков задач по префиксу
+	api.HandleFunc("/tasks/autocomplete", taskHandler.AutocompleteTitles).Methods("GET")
+	// Получение невыполненных задач в порядке по умолчанию для интерфейса
+	api.HandleFunc("/tasks/inbox", taskHandler.GetInbox).Methods("GET")
+	// Получение задач, сгруппированных по сроку выполнения
+	api.HandleFunc("/tasks/agenda", taskHandler.GetAgenda).Methods("GET")
+	// Получение задач в порядке зависимостей
+	api.HandleFunc("/tasks/ordered", taskHandler.GetOrderedTasks).Methods("GET")
+	// Получение невыполненных задач, упорядоченных по оценке приоритета и срочности
+	api.HandleFunc("/tasks/prioritized", taskHandler.GetPrioritizedTasks).Methods("GET")
+	// Получение задач, выполненных сегодня
+	api.HandleFunc("/tasks/completed-today", taskHandler.GetCompletedToday).Methods("GET")
+	// Статистика созданных и выполненных задач по интервалам времени
+	api.HandleFunc("/tasks/completion-rate", taskHandler.GetCompletionRate).Methods("GET")
+	// Количество задач по статусам с теми же фильтрами, что и у списка задач
+	api.HandleFunc("/tasks/count", taskHandler.GetTaskCount).Methods("GET")
+	// Выгрузка задач в файл CSV или JSON
+	api.HandleFunc("/tasks/export", taskHandler.ExportTasks).Methods("GET")
+	// Создание задачи из шаблона
+	api.HandleFunc("/tasks/from-template/{templateId:[0-9]+}", templateHandler.CreateTaskFromTemplate).Methods("POST")
 	// Получение задачи по ID
-	r.HandleFunc("/tasks/{id:[0-9]+}", taskHandler.GetTaskByID).Methods("GET")
+	api.HandleFunc("/tasks/{id:[0-9]+}", taskHandler.GetTaskByID).Methods("GET")
+	// Получение задач, от которых зависит задача
+	api.HandleFunc("/tasks/{id:[0-9]+}/dependencies", taskHandler.GetDependencies).Methods("GET")
+	// Получение подзадач задачи
+	api.HandleFunc("/tasks/{id:[0-9]+}/subtasks", taskHandler.GetSubtasks).Methods("GET")
+	// Получение журнала изменений задачи
+	api.HandleFunc("/tasks/{id:[0-9]+}/history", taskHandler.GetTaskHistory).Methods("GET")
+	// Блокировка задачи с указанием причины
+	api.HandleFunc("/tasks/{id:[0-9]+}/block", taskHandler.BlockTask).Methods("POST")
+	// Снятие блокировки задачи
+	api.HandleFunc("/tasks/{id:[0-9]+}/unblock", taskHandler.UnblockTask).Methods("POST")
+	// Отметка задачи выполненной
+	api.HandleFunc("/tasks/{id:[0-9]+}/complete", taskHandler.CompleteTask).Methods("POST")
+	// Снятие отметки о выполнении задачи
+	api.HandleFunc("/tasks/{id:[0-9]+}/incomplete", taskHandler.IncompleteTask).Methods("POST")
+	// Создание копии задачи
+	api.HandleFunc("/tasks/{id:[0-9]+}/duplicate", taskHandler.DuplicateTask).Methods("POST")
+	// Перемещение задачи между двумя соседями при ручной сортировке
+	api.HandleFunc("/tasks/{id:[0-9]+}/move-between", taskHandler.MoveTaskBetween).Methods("POST")
 	// Обновление задачи по ID
-	r.HandleFunc("/tasks/{id:[0-9]+}", taskHandler.UpdateTask).Methods("PUT")
+	api.HandleFunc("/tasks/{id:[0-9]+}", taskHandler.UpdateTask).Methods("PUT")
+	// Частичное обновление задачи по ID
+	api.HandleFunc("/tasks/{id:[0-9]+}", taskHandler.PatchTask).Methods("PATCH")
 	// Удаление задачи по ID
-	r.HandleFunc("/tasks/{id:[0-9]+}", taskHandler.DeleteTask).Methods("DELETE")
+	api.HandleFunc("/tasks/{id:[0-9]+}", taskHandler.DeleteTask).Methods("DELETE")
+	// Восстановление удаленной задачи по ID
+	api.HandleFunc("/tasks/{id:[0-9]+}/restore", taskHandler.RestoreTask).Methods("POST")
+
+	// Настраиваем маршруты для работы с шаблонами задач
+	// Создание нового шаблона
+	api.HandleFunc("/templates", templateHandler.CreateTemplate).Methods("POST")
+	// Получение всех шаблонов
+	api.HandleFunc("/templates", templateHandler.GetTemplates).Methods("GET")
+
+	// При необходимости требуем Content-Length у запросов на запись
+	if cfg.Server.RequireContentLength {
+		r.Use(middleware.RequireContentLength)
+	}
+	// Ограничиваем размер тела запроса
+	r.Use(middleware.LimitBody(int64(cfg.Server.MaxBodyBytes)))
+
+	// Оборачиваем маршрутизатор в CORS, а затем в логирование запросов,
+	// чтобы в лог попадали и запросы, не дошедшие до маршрутов (в том числе отклоненные
+	// ограничением частоты)
+	corsOptions := []handlers.CORSOption{
+		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),                       // Разрешённые методы HTTP
+		handlers.AllowedHeaders([]string{"Authorization", "Content-Type", "X-API-Key", "If-Match", "X-Request-ID"}), // Разрешённые заголовки
+		handlers.ExposedHeaders([]string{"ETag", "X-Next-Cursor", "X-Request-ID"}),                                  // Заголовки ответа, доступные скриптам браузера
+		handlers.AllowedOrigins(cfg.Server.CORSAllowedOrigins),                                                      // Разрешённые источники
+	}
+	if cfg.Server.CORSAllowCredentials {
+		corsOptions = append(corsOptions, handlers.AllowCredentials())
+	}
+	handler := handlers.CORS(corsOptions...)(r)
+
+	// Ограничиваем частоту запросов с одного IP-адреса до маршрутизации,
+	// чтобы лишние запросы не доходили до обработчиков и базы данных
+	if cfg.Server.RateLimitPerMinute > 0 {
+		limiter := middleware.NewRateLimiter(cfg.Server.RateLimitPerMinute, cfg.Server.RateLimitBurst, time.Minute)
+		defer limiter.Stop()
+		handler = limiter.Middleware(handler)
+	}
+	handler = middleware.RequestLogger(logger, observers...)(handler)
 
-	// Создаём HTTP-сервер с конфигурацией CORS и маршрутизатором
+	// Создаём HTTP-сервер с подготовленным обработчиком запросов
 	server := &http.Server{
-		Addr: ":8000", // Адрес, на котором будет запущен сервер
-		Handler: handlers.CORS(
-			handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}), // Разрешённые методы HTTP
-			handlers.AllowedHeaders([]string{"Authorization", "Content-Type"}),           // Разрешённые заголовки
-		)(r), // Передача маршрутизатора в качестве обработчика запросов
+		Addr:           ":" + cfg.Server.Port,     // Адрес, на котором будет запущен сервер
+		Handler:        handler,                   // Обработчик запросов
+		MaxHeaderBytes: cfg.Server.MaxHeaderBytes, // Ограничение размера заголовков запроса
 	}
 
 	// Запуск сервера в отдельной горутине, чтобы не блокировать основной поток
 	go func() {
-		logger.Info("Server started on :8000")
+		logger.Info("Server started on "+server.Addr, "max_header_bytes", cfg.Server.MaxHeaderBytes, "max_body_bytes", cfg.Server.MaxBodyBytes)
 		// Запуск HTTP-сервера и логирование ошибок, если сервер не может быть запущен
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Error("Could not listen on :8000", "error", err)
+			logger.Error("Could not listen on "+server.Addr, "error", err)
 		}
 	}()
 
-	// Создание канала для получения сигналов прерывания (например, Ctrl+C)
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt)
-	<-quit // Ожидание сигнала прерывания
+	// Ожидание сигнала остановки (например, Ctrl+C); к этому моменту
+	// фоновые задачи уже получили отмену и завершают начатую работу
+	<-ctx.Done()
+	stop()
 
-	// Создаём контекст с таймаутом для корректного завершения работы сервера
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// Создаём контекст с таймаутом, общим для завершения работы сервера и фоновых задач
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	// Завершаем работу сервера с использованием созданного контекста
-	if err := server.Shutdown(ctx); err != nil {
+	if err := server.Shutdown(shutdownCtx); err != nil {
 		logger.Error("Server forced to shutdown", "error", err)
 	}
 
+	// Дожидаемся фоновых задач в пределах того же таймаута
+	workersDone := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(workersDone)
+	}()
+	select {
+	case <-workersDone:
+	case <-shutdownCtx.Done():
+		logger.Error("Background workers did not stop in time")
+	}
+
 	// Логируем сообщение о завершении работы сервера
 	logger.Info("Server exiting")
 }
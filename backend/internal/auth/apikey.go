@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const (
+	// apiKeyPrefix отличает API-ключи сервиса от других секретов, например в логах сканеров.
+	apiKeyPrefix = "tk_"
+	// calendarTokenPrefix отличает токены подписки на календарь от API-ключей.
+	calendarTokenPrefix = "cal_"
+	// secretLength - количество случайных байтов ключа или токена.
+	secretLength = 32
+	// APIKeyDisplayLength - длина начала ключа, которое сохраняется открыто,
+	// чтобы пользователь мог отличить свои ключи друг от друга.
+	APIKeyDisplayLength = len(apiKeyPrefix) + 8
+	// CalendarTokenDisplayLength - длина начала токена календаря, которое сохраняется открыто.
+	CalendarTokenDisplayLength = len(calendarTokenPrefix) + 8
+)
+
+// GenerateAPIKey создает новый случайный API-ключ вида tk_<64 шестнадцатеричных символа>.
+func GenerateAPIKey() (string, error) {
+	return generateSecret(apiKeyPrefix)
+}
+
+// GenerateCalendarToken создает новый случайный токен календаря вида cal_<64 шестнадцатеричных символа>.
+func GenerateCalendarToken() (string, error) {
+	return generateSecret(calendarTokenPrefix)
+}
+
+// generateSecret создает случайный секрет из secretLength байтов с префиксом prefix.
+func generateSecret(prefix string) (string, error) {
+	secret := make([]byte, secretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(secret), nil
+}
+
+// HashAPIKey возвращает SHA-256 API-ключа или токена календаря в шестнадцатеричном
+// виде для хранения и поиска.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
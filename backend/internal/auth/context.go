@@ -0,0 +1,21 @@
+package auth
+
+import "context"
+
+// contextKey - тип ключей контекста пакета, не пересекающийся с ключами других пакетов.
+type contextKey int
+
+// userIDKey - ключ контекста запроса, под которым хранится ID аутентифицированного пользователя.
+const userIDKey contextKey = iota
+
+// WithUserID возвращает копию контекста с ID аутентифицированного пользователя.
+func WithUserID(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+// UserID возвращает ID аутентифицированного пользователя из контекста запроса.
+// Второе значение равно false, если запрос не прошел аутентификацию.
+func UserID(ctx context.Context) (int, bool) {
+	userID, ok := ctx.Value(userIDKey).(int)
+	return userID, ok
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidToken возвращается для токенов с неверной подписью, неподдерживаемым
+// алгоритмом, истекшим сроком действия или некорректной структурой.
+var ErrInvalidToken = errors.New("invalid token")
+
+// IssueToken выпускает JWT (HS256) для пользователя userID, подписанный секретом secret
+// и действующий в течение ttl с момента now. ID пользователя хранится в sub в виде строки,
+// как того требует спецификация JWT.
+func IssueToken(secret []byte, userID int, ttl time.Duration, now time.Time) (string, error) {
+	claims := jwt.RegisteredClaims{
+		Subject:   strconv.Itoa(userID),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// ParseToken проверяет подпись и срок действия токена на момент now
+// и возвращает ID пользователя, для которого он выпущен. Принимаются только
+// токены HS256 со сроком действия, что исключает подмену алгоритма (например, alg=none).
+func ParseToken(secret []byte, token string, now time.Time) (int, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+	)
+	var claims jwt.RegisteredClaims
+	keyFunc := func(*jwt.Token) (interface{}, error) { return secret, nil }
+	if _, err := parser.ParseWithClaims(token, &claims, keyFunc); err != nil {
+		return 0, ErrInvalidToken
+	}
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil || userID <= 0 {
+		return 0, ErrInvalidToken
+	}
+	return userID, nil
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordCost - стоимость bcrypt для новых хешей паролей. Стоимость сохраняется
+// в самом хеше, поэтому ее можно увеличивать, не ломая проверку уже сохраненных паролей.
+const passwordCost = 12
+
+// HashPassword вычисляет хеш пароля bcrypt со случайной солью.
+// bcrypt учитывает не больше 72 байт пароля, более длинные пароли отклоняются.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword сообщает, соответствует ли пароль сохраненному хешу.
+// Ошибка возвращается только для хеша в неизвестном формате.
+func CheckPassword(hash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -1,11 +1,33 @@
 package config
 
 import (
+	"fmt"
+	"math"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+)
+
+// Режимы удаления подзадач при удалении родительской задачи (настройка SUBTASK_DELETE_MODE).
+const (
+	// SubtaskDeleteCascade - подзадачи на любой глубине удаляются вместе с задачей.
+	SubtaskDeleteCascade = "cascade"
+	// SubtaskDeleteOrphan - прямые подзадачи становятся задачами верхнего уровня
+	// и остаются вместе со своими подзадачами.
+	SubtaskDeleteOrphan = "orphan"
 )
 
 type Config struct {
-	DB DatabaseConfig
+	DB     DatabaseConfig
+	Server ServerConfig
+	App    AppConfig
+	Log    LogConfig
+	Auth   AuthConfig
+	Worker WorkerConfig
 }
 
 type DatabaseConfig struct {
@@ -15,17 +37,394 @@ type DatabaseConfig struct {
 	Password string
 	DBName   string
 	SSLMode  string
+
+	// WarmupConnections - количество соединений, открываемых заранее при старте,
+	// чтобы первые запросы не тратили время на установку соединения. 0 отключает прогрев.
+	WarmupConnections int
+
+	// MaxOpenConns ограничивает количество одновременно открытых соединений,
+	// чтобы под нагрузкой не исчерпать лимит соединений PostgreSQL.
+	MaxOpenConns int
+
+	// MaxIdleConns - количество простаивающих соединений, сохраняемых в пуле
+	// для повторного использования.
+	MaxIdleConns int
+
+	// ConnMaxLifetime - максимальное время жизни соединения; более старые соединения
+	// закрываются и открываются заново, например после переключения реплик.
+	ConnMaxLifetime time.Duration
+
+	// ConnectAttempts - количество попыток подключения при старте, пока база данных
+	// не станет доступна (например, когда контейнер PostgreSQL еще запускается).
+	ConnectAttempts int
+
+	// ConnectBackoff - пауза перед второй попыткой подключения; перед каждой
+	// следующей попыткой пауза удваивается.
+	ConnectBackoff time.Duration
+
+	// QueryTimeout ограничивает время операций с базой данных при обработке одного
+	// запроса к API. Отдельные обработчики (массовое создание, выгрузка) могут
+	// использовать больший таймаут.
+	QueryTimeout time.Duration
 }
 
-func LoadConfig() *Config {
-	return &Config{
+// ServerConfig содержит настройки HTTP-сервера.
+type ServerConfig struct {
+	// Port - TCP-порт, на котором сервер принимает запросы.
+	Port string
+
+	// MaxHeaderBytes ограничивает суммарный размер заголовков запроса в байтах.
+	MaxHeaderBytes int
+
+	// MaxBodyBytes ограничивает размер тела запроса в байтах; запросы с большим
+	// телом отклоняются ответом 413. 0 отключает ограничение.
+	MaxBodyBytes int
+
+	// RequireContentLength требует заголовок Content-Length у запросов на запись
+	// и отклоняет chunked-тела ответом 411.
+	RequireContentLength bool
+
+	// RateLimitPerMinute - максимальное количество запросов в минуту с одного IP-адреса.
+	// 0 отключает ограничение.
+	RateLimitPerMinute int
+
+	// RateLimitBurst - количество запросов, которое клиент может выполнить подряд
+	// сверх равномерного темпа. По умолчанию равно RateLimitPerMinute.
+	RateLimitBurst int
+
+	// MetricsEnabled включает сбор метрик запросов и пула соединений
+	// и их публикацию для Prometheus по адресу /metrics.
+	MetricsEnabled bool
+
+	// CORSAllowedOrigins - источники (например, https://app.example.com), которым
+	// браузер разрешает обращаться к API. По умолчанию разрешены все ("*").
+	CORSAllowedOrigins []string
+
+	// CORSAllowCredentials разрешает браузеру отправлять в запросах к API cookie
+	// и заголовки авторизации. Несовместимо с разрешением всех источников.
+	CORSAllowCredentials bool
+}
+
+// AppConfig содержит общие настройки поведения сервиса.
+type AppConfig struct {
+	// DefaultTimezone - IANA-имя часового пояса (например, Europe/Moscow),
+	// используемое для операций с датами, если запрос не указал свой.
+	DefaultTimezone string
+
+	// TitleFromDescription разрешает создавать задачу с пустым заголовком,
+	// если описание не пустое: заголовок берется из первой строки описания.
+	TitleFromDescription bool
+
+	// Debug включает отладочные возможности API, например план выполнения
+	// запросов через параметр explain=true. Не должен включаться в production.
+	Debug bool
+
+	// LogSQLArgs добавляет в лог ошибок SQL-запросов значения их аргументов.
+	// Аргументы могут содержать персональные данные, поэтому по умолчанию выключено.
+	LogSQLArgs bool
+
+	// CreateReturnMinimal отключает тело ответа на создание задачи по умолчанию
+	// (остаются только статус 201 и заголовок Location). Клиент может запросить
+	// тело заголовком "Prefer: return=representation".
+	CreateReturnMinimal bool
+
+	// ListEnvelope оборачивает ответ списка задач в объект с полями data, total,
+	// limit и offset вместо голого массива - для клиентов, которым удобнее
+	// получать метаданные страницы в теле ответа, а не в заголовках.
+	ListEnvelope bool
+
+	// PriorityScoreWeight - вес приоритета в оценке задачи для /tasks/prioritized:
+	// приоритет low, medium и high дает 1, 2 и 3 таких веса.
+	PriorityScoreWeight float64
+
+	// DueScoreWeight - вес срочности в оценке задачи для /tasks/prioritized:
+	// задача со сроком прямо сейчас получает один такой вес, просроченная - до двух.
+	DueScoreWeight float64
+
+	// DueScoreHorizon - за сколько до срока выполнения задача начинает
+	// получать вес срочности; срочность растет линейно по мере приближения срока.
+	DueScoreHorizon time.Duration
+
+	// DeletableStatuses - статусы, в которых задачу можно удалить (например, только
+	// pending и done, чтобы случайно не удалить задачу в работе). Пустой список
+	// разрешает удаление в любом статусе.
+	DeletableStatuses []string
+
+	// SubtaskDeleteMode определяет, что происходит с подзадачами удаляемой задачи:
+	// SubtaskDeleteCascade или SubtaskDeleteOrphan.
+	SubtaskDeleteMode string
+
+	// DueDateRequiredPriority - приоритет, начиная с которого у задачи должен быть
+	// задан срок выполнения. Пустое значение отключает проверку.
+	DueDateRequiredPriority string
+}
+
+// LogConfig содержит настройки логирования.
+type LogConfig struct {
+	// Level - минимальный уровень записей: debug, info, warn или error.
+	Level string
+
+	// Format - формат записей: json или text (удобнее читать при локальной разработке).
+	Format string
+}
+
+// AuthConfig содержит настройки аутентификации пользователей.
+type AuthConfig struct {
+	// JWTSecret - секрет подписи токенов доступа. Обязателен: без него сервер не стартует.
+	JWTSecret string
+
+	// TokenTTL - срок действия токена доступа, выданного при входе.
+	TokenTTL time.Duration
+
+	// LegacyOwnerEmail - адрес пользователя, которому передаются задачи и шаблоны
+	// без владельца, созданные до появления учетных записей. Пустое значение
+	// оставляет их без владельца.
+	LegacyOwnerEmail string
+
+	// AdminEmails - адреса пользователей, которым доступны служебные маршруты /admin
+	// (в нижнем регистре). Пустой список закрывает эти маршруты для всех.
+	AdminEmails []string
+}
+
+// WorkerConfig содержит настройки фоновых задач сервиса.
+type WorkerConfig struct {
+	// RecurrenceInterval - периодичность создания следующих повторений
+	// выполненных повторяющихся задач.
+	RecurrenceInterval time.Duration
+
+	// ReminderInterval - периодичность проверки наступивших напоминаний о задачах.
+	ReminderInterval time.Duration
+
+	// ReminderWebhookURL - адрес, на который POST-запросом отправляются напоминания.
+	// Пустое значение отключает отправку напоминаний.
+	ReminderWebhookURL string
+}
+
+// LoadConfig загружает конфигурацию из переменных окружения. Если задана переменная
+// CONFIG_FILE, недостающие значения берутся из указанного файла конфигурации
+// (JSON или YAML): переменные окружения имеют приоритет над файлом, а файл -
+// над значениями по умолчанию. Возвращает ошибку, если файл не удалось прочитать
+// или не заданы обязательные настройки.
+func LoadConfig() (*Config, error) {
+	s := settings{}
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		file, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		s.file = file
+	}
+
+	cfg := &Config{
 		DB: DatabaseConfig{
-			Host:     os.Getenv("DB_HOST"),
-			Port:     os.Getenv("DB_PORT"),
-			User:     os.Getenv("DB_USER"),
-			Password: os.Getenv("DB_PASSWORD"),
-			DBName:   os.Getenv("DB_NAME"),
-			SSLMode:  os.Getenv("DB_SSLMODE"),
+			Host:     s.getString("DB_HOST", ""),
+			Port:     s.getString("DB_PORT", ""),
+			User:     s.getString("DB_USER", ""),
+			Password: s.getString("DB_PASSWORD", ""),
+			DBName:   s.getString("DB_NAME", ""),
+			SSLMode:  s.getString("DB_SSLMODE", ""),
+
+			WarmupConnections: s.getInt("DB_WARMUP_CONNECTIONS", 0),
+			MaxOpenConns:      s.getInt("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:      s.getInt("DB_MAX_IDLE_CONNS", 5),
+			ConnMaxLifetime:   s.getDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute),
+			ConnectAttempts:   s.getInt("DB_CONNECT_ATTEMPTS", 10),
+			ConnectBackoff:    s.getDuration("DB_CONNECT_BACKOFF", 500*time.Millisecond),
+			QueryTimeout:      s.getDuration("DB_QUERY_TIMEOUT", 5*time.Second),
+		},
+		Server: ServerConfig{
+			Port:                 s.getString("SERVER_PORT", "8000"),
+			MaxHeaderBytes:       s.getInt("SERVER_MAX_HEADER_BYTES", 1<<20),
+			MaxBodyBytes:         s.getNonNegativeInt("SERVER_MAX_BODY_BYTES", 1<<20),
+			RequireContentLength: s.getBool("SERVER_REQUIRE_CONTENT_LENGTH", false),
+			RateLimitPerMinute:   s.getInt("RATE_LIMIT_PER_MINUTE", 0),
+			MetricsEnabled:       s.getBool("METRICS_ENABLED", false),
+			CORSAllowedOrigins:   s.getList("CORS_ALLOWED_ORIGINS", []string{"*"}),
+			CORSAllowCredentials: s.getBool("CORS_ALLOW_CREDENTIALS", false),
 		},
+		App: AppConfig{
+			DefaultTimezone:         s.getString("DEFAULT_TIMEZONE", "UTC"),
+			TitleFromDescription:    s.getBool("TITLE_FROM_DESCRIPTION", false),
+			Debug:                   s.getBool("DEBUG", false),
+			LogSQLArgs:              s.getBool("LOG_SQL_ARGS", false),
+			CreateReturnMinimal:     s.getBool("CREATE_RETURN_MINIMAL", false),
+			ListEnvelope:            s.getBool("LIST_ENVELOPE", false),
+			PriorityScoreWeight:     s.getFloat("PRIORITY_SCORE_WEIGHT", 1),
+			DueScoreWeight:          s.getFloat("DUE_SCORE_WEIGHT", 3),
+			DueScoreHorizon:         s.getDuration("DUE_SCORE_HORIZON", 7*24*time.Hour),
+			DeletableStatuses:       s.getList("DELETABLE_STATUSES", nil),
+			SubtaskDeleteMode:       s.getString("SUBTASK_DELETE_MODE", SubtaskDeleteCascade),
+			DueDateRequiredPriority: s.getString("DUE_DATE_REQUIRED_PRIORITY", ""),
+		},
+		Log: LogConfig{
+			Level:  s.getString("LOG_LEVEL", "info"),
+			Format: s.getString("LOG_FORMAT", "json"),
+		},
+		Auth: AuthConfig{
+			JWTSecret:        s.getString("JWT_SECRET", ""),
+			TokenTTL:         s.getDuration("JWT_TTL", 24*time.Hour),
+			LegacyOwnerEmail: strings.ToLower(strings.TrimSpace(s.getString("LEGACY_OWNER_EMAIL", ""))),
+			AdminEmails:      s.getList("ADMIN_EMAILS", nil),
+		},
+		Worker: WorkerConfig{
+			RecurrenceInterval: s.getDuration("RECURRENCE_INTERVAL", time.Minute),
+			ReminderInterval:   s.getDuration("REMINDER_INTERVAL", time.Minute),
+			ReminderWebhookURL: s.getString("REMINDER_WEBHOOK_URL", ""),
+		},
+	}
+	for i, email := range cfg.Auth.AdminEmails {
+		cfg.Auth.AdminEmails[i] = strings.ToLower(email)
+	}
+	cfg.Server.RateLimitBurst = s.getInt("RATE_LIMIT_BURST", cfg.Server.RateLimitPerMinute)
+	// PORT поддерживается для платформ, которые сами назначают порт (например, Heroku),
+	// поэтому он важнее порта из файла, но не переменной SERVER_PORT
+	if port := os.Getenv("PORT"); port != "" && os.Getenv("SERVER_PORT") == "" {
+		cfg.Server.Port = port
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// validate проверяет, что заданы все обязательные настройки: параметры подключения
+// к базе данных и секрет подписи токенов. Без них сервис не может работать,
+// а ошибка подключения с пустыми параметрами мало что объясняет, поэтому
+// в ошибке перечисляются все незаданные переменные сразу.
+func (c *Config) validate() error {
+	required := []struct {
+		env   string
+		value string
+	}{
+		{"DB_HOST", c.DB.Host},
+		{"DB_PORT", c.DB.Port},
+		{"DB_USER", c.DB.User},
+		{"DB_PASSWORD", c.DB.Password},
+		{"DB_NAME", c.DB.DBName},
+		{"JWT_SECRET", c.Auth.JWTSecret},
+	}
+
+	var missing []string
+	for _, setting := range required {
+		if setting.value == "" {
+			missing = append(missing, setting.env)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required config: %s (set the environment variables or the corresponding settings in CONFIG_FILE)", strings.Join(missing, ", "))
+	}
+
+	if mode := c.App.SubtaskDeleteMode; mode != SubtaskDeleteCascade && mode != SubtaskDeleteOrphan {
+		return fmt.Errorf("SUBTASK_DELETE_MODE must be %s or %s, got %q", SubtaskDeleteCascade, SubtaskDeleteOrphan, mode)
+	}
+	for _, status := range c.App.DeletableStatuses {
+		if !models.IsValidStatus(status) {
+			return fmt.Errorf("DELETABLE_STATUSES: unknown status %q: must be one of %s", status, strings.Join(models.Statuses, ", "))
+		}
+	}
+
+	if p := c.App.DueDateRequiredPriority; p != "" && !models.IsValidPriority(p) {
+		return fmt.Errorf("DUE_DATE_REQUIRED_PRIORITY: unknown priority %q: must be one of %s", p, strings.Join(models.Priorities, ", "))
+	}
+
+	// Браузеры не принимают ответы с учетными данными от API, разрешающего все источники
+	if c.Server.CORSAllowCredentials && slices.Contains(c.Server.CORSAllowedOrigins, "*") {
+		return fmt.Errorf("CORS_ALLOW_CREDENTIALS requires CORS_ALLOWED_ORIGINS to list specific origins instead of *")
+	}
+	return nil
+}
+
+// settings - источник значений настроек: переменная окружения, если она задана
+// и не пуста, иначе значение из файла конфигурации.
+type settings struct {
+	file map[string]string
+}
+
+// lookup возвращает значение настройки key или пустую строку, если она не задана.
+func (s settings) lookup(key string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return s.file[key]
+}
+
+// getString возвращает значение настройки key
+// или значение по умолчанию def, если настройка не задана.
+func (s settings) getString(key, def string) string {
+	if value := s.lookup(key); value != "" {
+		return value
+	}
+	return def
+}
+
+// getBool возвращает логическое значение настройки key.
+// Если настройка не задана или не является корректным логическим значением,
+// возвращается значение по умолчанию def.
+func (s settings) getBool(key string, def bool) bool {
+	value, err := strconv.ParseBool(s.lookup(key))
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// getInt возвращает целочисленное значение настройки key.
+// Если настройка не задана или не является положительным целым числом,
+// возвращается значение по умолчанию def.
+func (s settings) getInt(key string, def int) int {
+	value, err := strconv.Atoi(s.lookup(key))
+	if err != nil || value <= 0 {
+		return def
+	}
+	return value
+}
+
+// getNonNegativeInt возвращает целочисленное значение настройки key, допуская 0
+// (например, для отключения ограничения). Если настройка не задана или не является
+// неотрицательным целым числом, возвращается значение по умолчанию def.
+func (s settings) getNonNegativeInt(key string, def int) int {
+	value, err := strconv.Atoi(s.lookup(key))
+	if err != nil || value < 0 {
+		return def
+	}
+	return value
+}
+
+// getFloat возвращает дробное значение настройки key, допуская 0 (например, чтобы
+// не учитывать составляющую оценки). Если настройка не задана или не является
+// неотрицательным числом, возвращается значение по умолчанию def.
+func (s settings) getFloat(key string, def float64) float64 {
+	value, err := strconv.ParseFloat(s.lookup(key), 64)
+	if err != nil || value < 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		return def
+	}
+	return value
+}
+
+// getList возвращает список значений настройки key, разделенных запятыми,
+// без пробелов по краям и пустых элементов. Если настройка не задана
+// или не содержит значений, возвращается значение по умолчанию def.
+func (s settings) getList(key string, def []string) []string {
+	var values []string
+	for _, value := range strings.Split(s.lookup(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return def
+	}
+	return values
+}
+
+// getDuration возвращает длительность из настройки key в формате
+// time.ParseDuration (например, 30m или 12h). Если настройка не задана или
+// не является положительной длительностью, возвращается значение по умолчанию def.
+func (s settings) getDuration(key string, def time.Duration) time.Duration {
+	value, err := time.ParseDuration(s.lookup(key))
+	if err != nil || value <= 0 {
+		return def
 	}
+	return value
 }
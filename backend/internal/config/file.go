@@ -0,0 +1,161 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileSettings сопоставляет настройки файла конфигурации вида раздел.ключ
+// с переменными окружения, которые их переопределяют.
+var fileSettings = map[string]string{
+	"db.host":               "DB_HOST",
+	"db.port":               "DB_PORT",
+	"db.user":               "DB_USER",
+	"db.password":           "DB_PASSWORD",
+	"db.name":               "DB_NAME",
+	"db.sslmode":            "DB_SSLMODE",
+	"db.warmup_connections": "DB_WARMUP_CONNECTIONS",
+	"db.max_open_conns":     "DB_MAX_OPEN_CONNS",
+	"db.max_idle_conns":     "DB_MAX_IDLE_CONNS",
+	"db.conn_max_lifetime":  "DB_CONN_MAX_LIFETIME",
+	"db.connect_attempts":   "DB_CONNECT_ATTEMPTS",
+	"db.connect_backoff":    "DB_CONNECT_BACKOFF",
+	"db.query_timeout":      "DB_QUERY_TIMEOUT",
+
+	"server.port":                   "SERVER_PORT",
+	"server.max_header_bytes":       "SERVER_MAX_HEADER_BYTES",
+	"server.max_body_bytes":         "SERVER_MAX_BODY_BYTES",
+	"server.require_content_length": "SERVER_REQUIRE_CONTENT_LENGTH",
+	"server.rate_limit_per_minute":  "RATE_LIMIT_PER_MINUTE",
+	"server.rate_limit_burst":       "RATE_LIMIT_BURST",
+	"server.metrics_enabled":        "METRICS_ENABLED",
+	"server.cors_allowed_origins":   "CORS_ALLOWED_ORIGINS",
+	"server.cors_allow_credentials": "CORS_ALLOW_CREDENTIALS",
+
+	"app.default_timezone":           "DEFAULT_TIMEZONE",
+	"app.title_from_description":     "TITLE_FROM_DESCRIPTION",
+	"app.debug":                      "DEBUG",
+	"app.log_sql_args":               "LOG_SQL_ARGS",
+	"app.create_return_minimal":      "CREATE_RETURN_MINIMAL",
+	"app.list_envelope":              "LIST_ENVELOPE",
+	"app.priority_score_weight":      "PRIORITY_SCORE_WEIGHT",
+	"app.due_score_weight":           "DUE_SCORE_WEIGHT",
+	"app.due_score_horizon":          "DUE_SCORE_HORIZON",
+	"app.deletable_statuses":         "DELETABLE_STATUSES",
+	"app.subtask_delete_mode":        "SUBTASK_DELETE_MODE",
+	"app.due_date_required_priority": "DUE_DATE_REQUIRED_PRIORITY",
+
+	"log.level":  "LOG_LEVEL",
+	"log.format": "LOG_FORMAT",
+
+	"auth.jwt_secret":         "JWT_SECRET",
+	"auth.jwt_ttl":            "JWT_TTL",
+	"auth.legacy_owner_email": "LEGACY_OWNER_EMAIL",
+	"auth.admin_emails":       "ADMIN_EMAILS",
+
+	"worker.recurrence_interval":  "RECURRENCE_INTERVAL",
+	"worker.reminder_interval":    "REMINDER_INTERVAL",
+	"worker.reminder_webhook_url": "REMINDER_WEBHOOK_URL",
+}
+
+// readConfigFile читает файл конфигурации path и возвращает его значения
+// по именам соответствующих переменных окружения. Формат определяется
+// по расширению: .json или .yaml/.yml. Неизвестные настройки считаются ошибкой,
+// чтобы опечатка в имени не оставляла значение по умолчанию незаметно.
+func readConfigFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var sections map[string]map[string]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		sections, err = parseJSONConfig(content)
+	case ".yaml", ".yml":
+		sections, err = parseYAMLConfig(content)
+	default:
+		return nil, fmt.Errorf("%s: config file must have .json, .yaml or .yml extension", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	var unknown []string
+	for section, settings := range sections {
+		for key, value := range settings {
+			name := section + "." + key
+			env, ok := fileSettings[name]
+			if !ok {
+				unknown = append(unknown, name)
+				continue
+			}
+			values[env] = value
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%s: unknown settings: %s", path, strings.Join(unknown, ", "))
+	}
+	return values, nil
+}
+
+// parseJSONConfig разбирает файл конфигурации в формате JSON: объект разделов,
+// каждый из которых - объект настроек со строковыми, числовыми или логическими значениями.
+func parseJSONConfig(content []byte) (map[string]map[string]string, error) {
+	var raw map[string]map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+	return settingStrings(raw)
+}
+
+// parseYAMLConfig разбирает файл конфигурации в формате YAML: разделы верхнего
+// уровня ("db:") с вложенными настройками со строковыми, числовыми или логическими значениями.
+func parseYAMLConfig(content []byte) (map[string]map[string]string, error) {
+	var raw map[string]map[string]interface{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, err
+	}
+	return settingStrings(raw)
+}
+
+// settingStrings приводит значения настроек разобранного файла к строкам,
+// в которых они задаются в переменных окружения. Пустое значение (null)
+// равнозначно отсутствующей настройке.
+func settingStrings(raw map[string]map[string]interface{}) (map[string]map[string]string, error) {
+	sections := make(map[string]map[string]string, len(raw))
+	for section, settings := range raw {
+		sections[section] = make(map[string]string, len(settings))
+		for key, value := range settings {
+			switch v := value.(type) {
+			case nil:
+				sections[section][key] = ""
+			case string:
+				sections[section][key] = v
+			case json.Number:
+				sections[section][key] = v.String()
+			case int:
+				sections[section][key] = strconv.Itoa(v)
+			case float64:
+				sections[section][key] = strconv.FormatFloat(v, 'g', -1, 64)
+			case bool:
+				sections[section][key] = strconv.FormatBool(v)
+			default:
+				return nil, fmt.Errorf("%s.%s must be a string, number or boolean", section, key)
+			}
+		}
+	}
+	return sections, nil
+}
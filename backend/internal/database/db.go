@@ -4,17 +4,18 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/NickolaiP/taskApi/backend/internal/config"
+	"github.com/NickolaiP/taskApi/backend/internal/logger"
 
 	_ "github.com/lib/pq"
 )
 
-// Database определяет интерфейс для взаимодействия с базой данных.
-// Все методы интерфейса принимают контекст для управления временем выполнения
-// и отмены операций.
-type Database interface {
+// Querier определяет методы выполнения запросов, общие для базы данных и транзакции.
+// Функции, принимающие Querier, могут работать как вне транзакции, так и внутри нее.
+type Querier interface {
 	// Query выполняет запрос к базе данных и возвращает строки результата.
 	// Аргументы запроса передаются как ...interface{}.
 	Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
@@ -27,11 +28,41 @@ type Database interface {
 	// например, команды INSERT, UPDATE, DELETE.
 	// Аргументы запроса передаются как ...interface{}.
 	Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Database определяет интерфейс для взаимодействия с базой данных.
+// Все методы интерфейса принимают контекст для управления временем выполнения
+// и отмены операций.
+type Database interface {
+	Querier
+
+	// BeginTx начинает транзакцию. Запросы транзакции выполняются через
+	// возвращаемый Tx, который нужно завершить вызовом Commit или Rollback.
+	BeginTx(ctx context.Context) (Tx, error)
+
+	// Ping проверяет, что база данных доступна.
+	Ping(ctx context.Context) error
+
+	// Stats возвращает статистику пула соединений.
+	Stats() sql.DBStats
 
 	// Close закрывает соединение с базой данных.
 	Close() error
 }
 
+// Tx определяет интерфейс транзакции базы данных. Запросы транзакции
+// видны другим соединениям только после Commit; Rollback отменяет их.
+type Tx interface {
+	Querier
+
+	// Commit фиксирует транзакцию.
+	Commit() error
+
+	// Rollback отменяет транзакцию. После Commit вызов ничего не делает
+	// и возвращает sql.ErrTxDone, поэтому его удобно откладывать через defer.
+	Rollback() error
+}
+
 // PostgresDB реализует интерфейс Database для работы с базой данных PostgreSQL.
 // Внутри него используется встроенное соединение базы данных *sql.DB.
 type PostgresDB struct {
@@ -56,16 +87,59 @@ func (db *PostgresDB) Exec(ctx context.Context, query string, args ...interface{
 	return db.DB.ExecContext(ctx, query, args...)
 }
 
+// BeginTx начинает транзакцию с использованием контекста.
+// Этот метод реализует интерфейс Database.
+func (db *PostgresDB) BeginTx(ctx context.Context) (Tx, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresTx{Tx: tx}, nil
+}
+
+// Ping проверяет доступность базы данных с использованием контекста.
+// Этот метод реализует интерфейс Database.
+func (db *PostgresDB) Ping(ctx context.Context) error {
+	return db.DB.PingContext(ctx)
+}
+
+// Stats возвращает статистику пула соединений.
+// Этот метод реализует интерфейс Database.
+func (db *PostgresDB) Stats() sql.DBStats {
+	return db.DB.Stats()
+}
+
 // Close закрывает соединение с базой данных.
 // Этот метод реализует интерфейс Database.
 func (db *PostgresDB) Close() error {
 	return db.DB.Close()
 }
 
+// postgresTx реализует интерфейс Tx поверх *sql.Tx.
+type postgresTx struct {
+	*sql.Tx
+}
+
+// Query выполняет запрос в транзакции и возвращает строки результата.
+func (tx *postgresTx) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.Tx.QueryContext(ctx, query, args...)
+}
+
+// QueryRow выполняет запрос в транзакции и возвращает одну строку результата.
+func (tx *postgresTx) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRowContext(ctx, query, args...)
+}
+
+// Exec выполняет в транзакции запрос, который не возвращает строки результата.
+func (tx *postgresTx) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return tx.Tx.ExecContext(ctx, query, args...)
+}
+
 // NewPostgresDB создает и возвращает новый экземпляр PostgresDB, используя настройки из конфигурации.
-// Выполняется проверка подключения к базе данных для обеспечения его корректной работы.
+// Выполняется проверка подключения к базе данных с повторными попытками
+// (cfg.ConnectAttempts), а при заданном cfg.WarmupConnections - прогрев пула соединений.
 // При успешной проверке возвращается объект PostgresDB и nil, иначе возвращается ошибка.
-func NewPostgresDB(cfg config.DatabaseConfig) (Database, error) {
+func NewPostgresDB(cfg config.DatabaseConfig, logger *logger.Logger) (Database, error) {
 	// Формирование строки подключения к базе данных PostgreSQL.
 	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
 		cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.DBName, cfg.SSLMode)
@@ -76,14 +150,94 @@ func NewPostgresDB(cfg config.DatabaseConfig) (Database, error) {
 		return nil, err
 	}
 
-	// Проверка подключения к базе данных с использованием контекста.
-	// Таймаут установлен на 5 секунд для проверки доступности базы данных.
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := db.PingContext(ctx); err != nil {
+	// Настройка пула соединений. Прогретые соединения должны оставаться в пуле,
+	// поэтому количество простаивающих соединений не меньше прогреваемых,
+	// а прогревается не больше соединений, чем разрешено открыть.
+	warmup := min(cfg.WarmupConnections, cfg.MaxOpenConns)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(min(max(cfg.MaxIdleConns, warmup), cfg.MaxOpenConns))
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	// Проверка подключения к базе данных; при старте вместе с базой данных
+	// она может быть еще недоступна, поэтому проверка повторяется.
+	if err := pingWithRetry(db, cfg, logger); err != nil {
+		db.Close()
 		return nil, err
 	}
 
+	// Прогреваем пул соединений, если это включено в конфигурации.
+	if warmup > 0 {
+		warmCtx, warmCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer warmCancel()
+		warmed := warmUp(warmCtx, db, warmup)
+		logger.Info("Database connections warmed up", "requested", cfg.WarmupConnections, "warmed", warmed)
+	}
+
 	// Возвращаем объект PostgresDB, который реализует интерфейс Database.
 	return &PostgresDB{DB: db}, nil
 }
+
+// maxConnectBackoff ограничивает паузу между попытками подключения к базе данных.
+const maxConnectBackoff = 30 * time.Second
+
+// pingWithRetry проверяет доступность базы данных до cfg.ConnectAttempts раз
+// с экспоненциально растущей паузой между попытками, начиная с cfg.ConnectBackoff.
+// Каждая неудачная попытка записывается в лог. Возвращает ошибку последней попытки,
+// если база данных так и не стала доступна.
+func pingWithRetry(db *sql.DB, cfg config.DatabaseConfig, logger *logger.Logger) error {
+	delay := cfg.ConnectBackoff
+	for attempt := 1; ; attempt++ {
+		// Таймаут установлен на 5 секунд для каждой проверки доступности базы данных.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := db.PingContext(ctx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if attempt >= cfg.ConnectAttempts {
+			return fmt.Errorf("database is unavailable after %d attempts: %w", attempt, err)
+		}
+
+		logger.Warn("Database is not available, retrying",
+			"attempt", attempt, "max_attempts", cfg.ConnectAttempts, "retry_in", delay.String(), "error", err)
+		time.Sleep(delay)
+		delay = min(delay*2, maxConnectBackoff)
+	}
+}
+
+// warmUp одновременно открывает до n отдельных соединений, проверяет каждое из них
+// и возвращает их в пул. Возвращает количество успешно прогретых соединений.
+func warmUp(ctx context.Context, db *sql.DB, n int) int {
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		conns = make([]*sql.Conn, 0, n)
+	)
+
+	// Удерживаем соединения до окончания прогрева, чтобы пул
+	// не выдал одно и то же соединение нескольким горутинам.
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := db.Conn(ctx)
+			if err != nil {
+				return
+			}
+			if err := conn.PingContext(ctx); err != nil {
+				conn.Close()
+				return
+			}
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// Закрытие *sql.Conn возвращает соединение в пул, а не разрывает его.
+	for _, conn := range conns {
+		conn.Close()
+	}
+	return len(conns)
+}
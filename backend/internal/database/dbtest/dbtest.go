@@ -0,0 +1,231 @@
+// Package dbtest содержит реализацию database.Database в памяти для тестов
+// обработчиков: запросы не выполняются, а сопоставляются с заранее заданными
+// ответами, поэтому тестам не нужен PostgreSQL.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/NickolaiP/taskApi/backend/internal/database"
+)
+
+// Mock реализует database.Database поверх настоящего *sql.DB с драйвером в памяти.
+// Ответ на запрос задается правилом On: первое правило, фрагмент которого входит
+// в текст запроса, определяет строки, результат или ошибку. Запрос, не подходящий
+// ни под одно правило, завершается ошибкой, чтобы тест не проходил случайно.
+type Mock struct {
+	*database.PostgresDB
+
+	mu    sync.Mutex
+	rules []*Rule
+	calls []Call
+}
+
+// Rule - ответ Mock на запросы, содержащие фрагмент Fragment.
+type Rule struct {
+	Fragment string
+
+	columns      []string
+	rows         [][]driver.Value
+	rowsAffected int64
+	err          error
+}
+
+// Call - запрос, выполненный через Mock, с аргументами после преобразования драйвером.
+type Call struct {
+	Query string
+	Args  []interface{}
+}
+
+// New создает Mock без правил. Соединение закрывается вызовом Close.
+func New() *Mock {
+	m := &Mock{}
+	m.PostgresDB = &database.PostgresDB{DB: sql.OpenDB(connector{m})}
+	return m
+}
+
+// On добавляет правило для запросов, содержащих фрагмент fragment, и возвращает его
+// для настройки ответа. Правило без настройки отвечает пустым результатом:
+// QueryRow по нему возвращает sql.ErrNoRows.
+func (m *Mock) On(fragment string) *Rule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rule := &Rule{Fragment: fragment}
+	m.rules = append(m.rules, rule)
+	return rule
+}
+
+// Rows задает колонки columns и строки rows, которые возвращает запрос.
+func (r *Rule) Rows(columns []string, rows ...[]driver.Value) *Rule {
+	r.columns = columns
+	r.rows = rows
+	return r
+}
+
+// Affected задает количество строк, измененных командой.
+func (r *Rule) Affected(n int64) *Rule {
+	r.rowsAffected = n
+	return r
+}
+
+// Error задает ошибку, с которой завершается запрос.
+func (r *Rule) Error(err error) *Rule {
+	r.err = err
+	return r
+}
+
+// Calls возвращает выполненные запросы в порядке выполнения.
+func (m *Mock) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+// match записывает запрос query и возвращает подходящее для него правило.
+func (m *Mock) match(query string, args []driver.NamedValue) (*Rule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	call := Call{Query: query, Args: make([]interface{}, len(args))}
+	for i, arg := range args {
+		call.Args[i] = arg.Value
+	}
+	m.calls = append(m.calls, call)
+
+	for _, rule := range m.rules {
+		if strings.Contains(query, rule.Fragment) {
+			return rule, nil
+		}
+	}
+	return nil, fmt.Errorf("dbtest: unexpected query %q", query)
+}
+
+// connector создает соединения драйвера в памяти для sql.OpenDB.
+type connector struct {
+	m *Mock
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	return &conn{m: c.m}, nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return memoryDriver{}
+}
+
+// memoryDriver нужен только для connector.Driver: соединения открываются через connector.
+type memoryDriver struct{}
+
+func (memoryDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("dbtest: open the database with New")
+}
+
+// conn выполняет запросы, сопоставляя их с правилами Mock.
+type conn struct {
+	m *Mock
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("dbtest: prepared statements are not supported")
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return tx{}, nil
+}
+
+func (c *conn) BeginTx(ctx context.Context, _ driver.TxOptions) (driver.Tx, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return tx{}, nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// CheckNamedValue принимает аргументы запроса без преобразования, кроме
+// driver.Valuer, чтобы тесты видели те же значения, что получил бы PostgreSQL.
+func (c *conn) CheckNamedValue(arg *driver.NamedValue) error {
+	if valuer, ok := arg.Value.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		if err != nil {
+			return err
+		}
+		arg.Value = value
+	}
+	return nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	rule, err := c.m.match(query, args)
+	if err != nil {
+		return nil, err
+	}
+	if rule.err != nil {
+		return nil, rule.err
+	}
+	return &rows{columns: rule.columns, values: rule.rows}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	rule, err := c.m.match(query, args)
+	if err != nil {
+		return nil, err
+	}
+	if rule.err != nil {
+		return nil, rule.err
+	}
+	return driver.RowsAffected(rule.rowsAffected), nil
+}
+
+// tx - транзакция драйвера в памяти: Commit и Rollback ничего не делают.
+type tx struct{}
+
+func (tx) Commit() error {
+	return nil
+}
+
+func (tx) Rollback() error {
+	return nil
+}
+
+// rows возвращает строки правила по одной.
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+	next    int
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
@@ -0,0 +1,42 @@
+package database
+
+import "context"
+
+// renameLegacyTitlesQuery дополняет ID заголовки открытых задач без владельца,
+// совпадающие с заголовками открытых задач пользователя с адресом $1.
+const renameLegacyTitlesQuery = `UPDATE tasks t SET title = left(t.title, 255 - length(' (' || t.id || ')')) || ' (' || t.id || ')'
+	WHERE t.user_id IS NULL AND t.deleted_at IS NULL AND t.status <> 'done'
+		AND EXISTS (SELECT 1 FROM tasks e JOIN users u ON u.id = e.user_id WHERE u.email = $1
+			AND e.title = t.title AND e.deleted_at IS NULL AND e.status <> 'done')`
+
+// ClaimLegacyData передает задачи и шаблоны задач без владельца, созданные до появления
+// учетных записей, пользователю с адресом email в одной транзакции. Возвращает количество
+// переданных задач и шаблонов; если такого пользователя нет, ничего не меняет.
+// Открытая задача без владельца, заголовок которой совпадает с заголовком открытой задачи
+// пользователя, получает суффикс с ID, как в миграции 0023_task_open_title_unique.sql.
+func ClaimLegacyData(ctx context.Context, db Database, email string) (tasks, templates int64, err error) {
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	// После успешного Commit откат ничего не делает
+	defer tx.Rollback()
+
+	claim := func(table string) (int64, error) {
+		result, err := tx.Exec(ctx, "UPDATE "+table+" SET user_id = u.id FROM users u WHERE u.email = $1 AND "+table+".user_id IS NULL", email)
+		if err != nil {
+			return 0, err
+		}
+		return result.RowsAffected()
+	}
+	if _, err := tx.Exec(ctx, renameLegacyTitlesQuery, email); err != nil {
+		return 0, 0, err
+	}
+	if tasks, err = claim("tasks"); err != nil {
+		return 0, 0, err
+	}
+	if templates, err = claim("task_templates"); err != nil {
+		return 0, 0, err
+	}
+	return tasks, templates, tx.Commit()
+}
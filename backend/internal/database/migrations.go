@@ -2,31 +2,187 @@ package database
 
 import (
 	"context"
-	"log"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/logger"
 )
 
-// RunMigrations выполняет миграции базы данных, создавая необходимые таблицы,
-// если они еще не существуют. Это необходимо для обеспечения структуры
-// базы данных перед запуском приложения.
-func RunMigrations(db Database) {
-	// SQL-запрос для создания таблицы задач.
-	taskTable := `CREATE TABLE IF NOT EXISTS tasks (
-        id SERIAL PRIMARY KEY,
-        title VARCHAR(255) NOT NULL,
-        description TEXT NOT NULL,
-        due_date TIMESTAMP,
-        created_at TIMESTAMP NOT NULL,
-        updated_at TIMESTAMP NOT NULL
-    );`
-
-	// Создание контекста с таймаутом для выполнения SQL-запросов.
+// migrationFiles содержит SQL-файлы миграций вида NNNN_описание.sql.
+// Номер файла задает версию схемы и порядок применения миграций.
+//
+// Миграции до появления версионирования выполнялись при каждом старте, поэтому
+// первые файлы написаны идемпотентно: на существующей базе данных они применяются
+// без изменений и только записываются как выполненные. Новые миграции выполняются
+// ровно один раз и не обязаны быть идемпотентными.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationTimeout ограничивает время выполнения одной миграции: заполнение
+// новых колонок и построение индексов на больших таблицах может занимать время.
+const migrationTimeout = 5 * time.Minute
+
+// migration описывает одну миграцию схемы базы данных.
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// loadMigrations читает встроенные файлы миграций и возвращает их в порядке версий.
+// Файлы с некорректным именем или повторяющейся версией считаются ошибкой.
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]migration, 0, len(entries))
+	seen := make(map[int]string, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		prefix, _, ok := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: file name must start with a positive version number", name)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s have the same version %d", other, name, version)
+		}
+		seen[version] = name
+
+		content, err := fs.ReadFile(migrationFiles, "migrations/"+name)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration{version: version, name: name, sql: string(content)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
+
+// RunMigrations приводит схему базы данных к последней версии: применяет по порядку
+// миграции, которых еще нет в таблице schema_migrations. Каждая миграция выполняется
+// в отдельной транзакции вместе с записью о ее применении, поэтому при ошибке схема
+// остается в состоянии предыдущей версии.
+func RunMigrations(db Database, logger *logger.Logger) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	// Создание таблицы с версиями примененных миграций.
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+	_, err = db.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+        version INTEGER PRIMARY KEY,
+        name TEXT NOT NULL,
+        applied_at TIMESTAMP NOT NULL
+    );`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	// Применение миграций по порядку версий.
+	for _, m := range migrations {
+		applied, err := applyMigration(db, m)
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", m.name, err)
+		}
+		if applied {
+			logger.Info("Migration applied", "version", m.version, "name", m.name)
+		}
+	}
+	return nil
+}
+
+// applyMigration применяет миграцию m в транзакции, если она еще не применена.
+// Таблица schema_migrations блокируется на время транзакции, чтобы несколько
+// одновременно запущенных экземпляров сервиса не применили миграцию дважды.
+// Возвращает true, если миграция была применена этим вызовом.
+func applyMigration(db Database, m migration) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), migrationTimeout)
+	defer cancel()
 
-	// Выполнение SQL-запроса для создания таблицы задач.
-	_, err := db.Exec(ctx, taskTable)
+	tx, err := db.BeginTx(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return false, err
+	}
+	// После Commit откат ничего не делает
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(ctx, "LOCK TABLE schema_migrations IN EXCLUSIVE MODE"); err != nil {
+		return false, err
+	}
+
+	var applied bool
+	if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version=$1)", m.version).Scan(&applied); err != nil {
+		return false, err
+	}
+	if applied {
+		return false, nil
+	}
+
+	// Файл миграции может содержать несколько команд; без аргументов
+	// они выполняются одним запросом
+	if _, err := tx.Exec(ctx, m.sql); err != nil {
+		return false, err
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+		m.version, m.name, time.Now().Format(time.RFC3339))
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// MigrationInfo описывает миграцию схемы для отчета о состоянии схемы.
+// AppliedAt пусто у миграций, которые еще не применены.
+type MigrationInfo struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time
+}
+
+// MigrationStatus возвращает миграции, записанные в schema_migrations, в порядке версий
+// и встроенные в сервис миграции, которых там еще нет. Среди примененных могут быть
+// миграции, неизвестные этой версии сервиса, например после отката развертывания.
+func MigrationStatus(ctx context.Context, db Database) (applied, pending []MigrationInfo, err error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := db.Query(ctx, "SELECT version, name, applied_at FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	seen := make(map[int]bool)
+	for rows.Next() {
+		var m MigrationInfo
+		if err := rows.Scan(&m.Version, &m.Name, &m.AppliedAt); err != nil {
+			return nil, nil, err
+		}
+		seen[m.Version] = true
+		applied = append(applied, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	for _, m := range migrations {
+		if !seen[m.version] {
+			pending = append(pending, MigrationInfo{Version: m.version, Name: m.name})
+		}
 	}
+	return applied, pending, nil
 }
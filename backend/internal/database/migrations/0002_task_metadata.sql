@@ -0,0 +1,2 @@
+-- Произвольные метаданные задачи в формате JSON.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS metadata JSONB;
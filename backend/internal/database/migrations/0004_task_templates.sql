@@ -0,0 +1,9 @@
+-- Шаблоны для быстрого создания типовых задач.
+CREATE TABLE IF NOT EXISTS task_templates (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL,
+    due_in_days INTEGER,
+    created_at TIMESTAMP NOT NULL
+);
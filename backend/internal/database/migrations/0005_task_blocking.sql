@@ -0,0 +1,3 @@
+-- Признак блокировки задачи внешней причиной и сама причина.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS blocked BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS blocked_reason TEXT;
@@ -0,0 +1,8 @@
+-- Зависимости между задачами: task_id не может быть выполнена раньше depends_on_id.
+-- Связи удаляются вместе с любой из задач.
+CREATE TABLE IF NOT EXISTS task_dependencies (
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    depends_on_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, depends_on_id),
+    CHECK (task_id <> depends_on_id)
+);
@@ -0,0 +1,2 @@
+-- Индекс для поиска заголовков по префиксу без учета регистра.
+CREATE INDEX IF NOT EXISTS tasks_title_prefix_idx ON tasks (lower(title) text_pattern_ops);
@@ -0,0 +1,4 @@
+-- Время перевода задачи в статус done. Для уже выполненных задач
+-- в качестве приближения берется время последнего изменения.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS completed_at TIMESTAMP;
+UPDATE tasks SET completed_at = updated_at WHERE status = 'done' AND completed_at IS NULL;
@@ -0,0 +1,12 @@
+-- Дробная позиция задачи для ручной сортировки. Новые задачи получают позицию
+-- из последовательности и попадают в конец списка; существующие задачи
+-- нумеруются в порядке ID.
+CREATE SEQUENCE IF NOT EXISTS tasks_position_seq;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS position DOUBLE PRECISION;
+UPDATE tasks SET position = numbered.position
+    FROM (SELECT id, nextval('tasks_position_seq') * 1024 AS position
+        FROM (SELECT id FROM tasks WHERE position IS NULL ORDER BY id) AS pending) AS numbered
+    WHERE tasks.id = numbered.id;
+ALTER TABLE tasks ALTER COLUMN position SET DEFAULT nextval('tasks_position_seq') * 1024;
+ALTER TABLE tasks ALTER COLUMN position SET NOT NULL;
+CREATE INDEX IF NOT EXISTS tasks_position_idx ON tasks (position);
@@ -0,0 +1,2 @@
+-- Время мягкого удаления задачи; NULL означает, что задача не удалена.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
@@ -0,0 +1,3 @@
+-- Индекс для полнотекстового поиска по заголовку и описанию.
+CREATE INDEX IF NOT EXISTS tasks_fulltext_idx ON tasks
+    USING GIN (to_tsvector('simple', title || ' ' || description));
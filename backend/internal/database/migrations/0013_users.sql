@@ -0,0 +1,10 @@
+-- Учетные записи пользователей и владелец задачи. Задачи, созданные
+-- до появления пользователей, остаются без владельца и не видны никому.
+CREATE TABLE IF NOT EXISTS users (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS user_id INTEGER REFERENCES users(id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS tasks_user_id_idx ON tasks (user_id);
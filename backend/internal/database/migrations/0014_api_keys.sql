@@ -0,0 +1,12 @@
+-- API-ключи для интеграций между серверами. Хранится только SHA-256 ключа:
+-- ключ случайный и длинный, поэтому медленное хеширование не нужно,
+-- а по хешу ключ можно найти индексом.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
@@ -0,0 +1,8 @@
+-- Повторяющиеся задачи: правило повторения, количество единиц между повторениями
+-- и задача, из которой создано повторение. Уникальность recurred_from гарантирует,
+-- что у задачи не больше одного следующего повторения.
+ALTER TABLE tasks ADD COLUMN recurrence VARCHAR(10)
+    CHECK (recurrence IN ('daily', 'weekly', 'monthly'));
+ALTER TABLE tasks ADD COLUMN recurrence_interval INTEGER NOT NULL DEFAULT 1
+    CHECK (recurrence_interval > 0);
+ALTER TABLE tasks ADD COLUMN recurred_from INTEGER UNIQUE REFERENCES tasks(id) ON DELETE SET NULL;
@@ -0,0 +1,5 @@
+-- Напоминания о задачах: время напоминания и признак того, что оно уже отправлено.
+ALTER TABLE tasks ADD COLUMN remind_at TIMESTAMP;
+ALTER TABLE tasks ADD COLUMN reminded BOOLEAN NOT NULL DEFAULT false;
+-- Частичный индекс для поиска неотправленных напоминаний.
+CREATE INDEX tasks_pending_reminders_idx ON tasks (remind_at) WHERE NOT reminded;
@@ -0,0 +1,6 @@
+-- Подзадачи: ссылка на родительскую задачу. Удаление задач в API мягкое и каскадно
+-- помечает удаленными подзадачи; ON DELETE CASCADE удаляет их вместе с родителем
+-- при физическом удалении строки.
+ALTER TABLE tasks ADD COLUMN parent_id INTEGER REFERENCES tasks(id) ON DELETE CASCADE;
+-- Индекс для выборки подзадач по родителю.
+CREATE INDEX tasks_parent_id_idx ON tasks (parent_id);
@@ -0,0 +1,15 @@
+-- Метки задач. Метки принадлежат пользователю и уникальны по имени в пределах
+-- пользователя; связи с задачами удаляются вместе с задачей или меткой.
+CREATE TABLE tags (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    UNIQUE (user_id, name)
+);
+CREATE TABLE task_tags (
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, tag_id)
+);
+-- Индекс для фильтрации задач по метке.
+CREATE INDEX task_tags_tag_id_idx ON task_tags (tag_id);
@@ -0,0 +1,3 @@
+-- Версия задачи для оптимистичной блокировки: увеличивается при каждом изменении
+-- задачи и передается клиентам в заголовке ETag.
+ALTER TABLE tasks ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,12 @@
+-- Журнал изменений задач: кто, когда и как изменил задачу. Записи не ссылаются
+-- на tasks и users внешними ключами, чтобы журнал сохранялся и после окончательного
+-- удаления задачи или пользователя. user_id NULL - изменение, сделанное самим сервисом.
+CREATE TABLE IF NOT EXISTS task_audit (
+    id BIGSERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL,
+    user_id INTEGER,
+    action VARCHAR(20) NOT NULL,
+    changes JSONB,
+    created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS task_audit_task_id_idx ON task_audit (task_id, id);
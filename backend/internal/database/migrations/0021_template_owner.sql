@@ -0,0 +1,5 @@
+-- Владелец шаблона задачи. Шаблоны, созданные до появления владельца, как и такие
+-- же задачи (см. 0013_users.sql), остаются без владельца и не видны никому, пока
+-- их не получит пользователь из настройки LEGACY_OWNER_EMAIL.
+ALTER TABLE task_templates ADD COLUMN IF NOT EXISTS user_id INTEGER REFERENCES users(id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS task_templates_user_id_idx ON task_templates (user_id);
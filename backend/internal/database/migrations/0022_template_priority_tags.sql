@@ -0,0 +1,4 @@
+-- Приоритет и метки, которые получает задача, созданная из шаблона.
+ALTER TABLE task_templates ADD COLUMN IF NOT EXISTS priority VARCHAR(10) NOT NULL DEFAULT 'medium'
+    CHECK (priority IN ('low', 'medium', 'high'));
+ALTER TABLE task_templates ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
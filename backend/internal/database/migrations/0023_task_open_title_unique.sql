@@ -0,0 +1,10 @@
+-- Заголовок открытой задачи уникален у пользователя: по нему PUT /tasks/by-title/{title}
+-- находит задачу через INSERT ... ON CONFLICT. Удаленные и выполненные задачи в ограничение
+-- не входят: выполненная повторяющаяся задача оставляет свой заголовок следующему повторению.
+-- Совпадающие заголовки существующих открытых задач, кроме самой ранней, дополняются ID
+-- задачи, чтобы ни одна задача не была удалена или скрыта.
+UPDATE tasks t SET title = left(t.title, 255 - length(' (' || t.id || ')')) || ' (' || t.id || ')'
+WHERE t.deleted_at IS NULL AND t.status <> 'done'
+    AND EXISTS (SELECT 1 FROM tasks e WHERE e.user_id IS NOT DISTINCT FROM t.user_id AND e.title = t.title
+        AND e.deleted_at IS NULL AND e.status <> 'done' AND e.id < t.id);
+CREATE UNIQUE INDEX IF NOT EXISTS tasks_user_open_title_idx ON tasks (user_id, title) WHERE deleted_at IS NULL AND status <> 'done';
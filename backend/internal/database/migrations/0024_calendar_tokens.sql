@@ -0,0 +1,12 @@
+-- Токены подписки на календарь задач. Токен передается в адресе календаря, поэтому
+-- он дает доступ только к GET /tasks/calendar.ics и отзывается отдельно от API-ключей.
+-- Как и у API-ключей, хранится только SHA-256 токена.
+CREATE TABLE IF NOT EXISTS calendar_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    prefix VARCHAR(16) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP
+);
@@ -0,0 +1,77 @@
+package hand
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/database"
+	"github.com/NickolaiP/taskApi/backend/internal/logger"
+)
+
+// adminHandler представляет собой структуру обработчика служебных маршрутов /admin.
+// Доступ к ним проверяет middleware.RequireAdmin.
+type adminHandler struct {
+	db           database.Database
+	logger       *logger.Logger
+	queryTimeout time.Duration
+}
+
+// NewAdminHandler создает новый экземпляр adminHandler с заданными базой данных, логгером
+// и таймаутом операций с базой данных на один запрос.
+func NewAdminHandler(db database.Database, logger *logger.Logger, queryTimeout time.Duration) *adminHandler {
+	return &adminHandler{
+		db:           db,
+		logger:       logger,
+		queryTimeout: queryTimeout,
+	}
+}
+
+// schemaMigration описывает миграцию в ответе о состоянии схемы.
+type schemaMigration struct {
+	Version   int    `json:"version" xml:"version"`
+	Name      string `json:"name" xml:"name"`
+	AppliedAt string `json:"applied_at,omitempty" xml:"applied_at,omitempty"`
+}
+
+// schemaStatus - ответ о состоянии схемы базы данных. Version - версия последней
+// примененной миграции (0, если не применена ни одна); списки всегда кодируются
+// как массивы, даже если они пусты.
+type schemaStatus struct {
+	Version int               `json:"version" xml:"version"`
+	Applied []schemaMigration `json:"applied" xml:"applied>migration"`
+	Pending []schemaMigration `json:"pending" xml:"pending>migration"`
+}
+
+// GetSchema обрабатывает запрос на получение состояния схемы базы данных: текущей версии,
+// примененных миграций со временем применения и встроенных в сервис миграций, которые
+// еще не применены. Помогает проверить схему при развертывании.
+func (h *adminHandler) GetSchema(w http.ResponseWriter, r *http.Request) {
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	applied, pending, err := database.MigrationStatus(ctx, h.db)
+	if err != nil {
+		// Логируем и возвращаем ошибку сервера при сбое запроса
+		logger.FromContext(ctx, h.logger).Error("Failed to read schema migrations", "error", err)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+
+	status := schemaStatus{Applied: []schemaMigration{}, Pending: []schemaMigration{}}
+	for _, m := range applied {
+		status.Version = max(status.Version, m.Version)
+		status.Applied = append(status.Applied, schemaMigration{
+			Version:   m.Version,
+			Name:      m.Name,
+			AppliedAt: m.AppliedAt.Format(time.RFC3339),
+		})
+	}
+	for _, m := range pending {
+		status.Pending = append(status.Pending, schemaMigration{Version: m.Version, Name: m.Name})
+	}
+
+	// Возвращаем состояние схемы в формате JSON
+	writeResponse(logger.FromContext(r.Context(), h.logger), w, r, http.StatusOK, status)
+}
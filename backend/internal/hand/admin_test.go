@@ -0,0 +1,60 @@
+package hand
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/database/dbtest"
+	"github.com/NickolaiP/taskApi/backend/internal/logger"
+)
+
+func TestGetSchema(t *testing.T) {
+	appliedAt := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	t.Run("applied and pending migrations", func(t *testing.T) {
+		db := dbtest.New()
+		defer db.Close()
+		db.On("FROM schema_migrations").Rows([]string{"version", "name", "applied_at"},
+			[]driver.Value{int64(1), "0001_create_tasks.sql", appliedAt},
+			[]driver.Value{int64(2), "0002_task_metadata.sql", appliedAt})
+
+		w := httptest.NewRecorder()
+		NewAdminHandler(db, logger.InitLogger(io.Discard, "error", "text"), time.Second).
+			GetSchema(w, httptest.NewRequest("GET", "/admin/schema", nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d; body: %s", w.Code, http.StatusOK, w.Body)
+		}
+		var got schemaStatus
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if got.Version != 2 || len(got.Applied) != 2 || got.Applied[1].AppliedAt != "2025-01-15T10:00:00Z" {
+			t.Errorf("applied = version %d %+v, want version 2 with two migrations", got.Version, got.Applied)
+		}
+		// Все встроенные миграции после второй еще не применены
+		if len(got.Pending) == 0 || got.Pending[0].Version != 3 || got.Pending[0].AppliedAt != "" {
+			t.Errorf("pending = %+v, want migrations from version 3 without applied_at", got.Pending)
+		}
+	})
+
+	t.Run("query fails", func(t *testing.T) {
+		db := dbtest.New()
+		defer db.Close()
+		db.On("FROM schema_migrations").Error(errors.New("connection reset"))
+
+		w := httptest.NewRecorder()
+		NewAdminHandler(db, logger.InitLogger(io.Discard, "error", "text"), time.Second).
+			GetSchema(w, httptest.NewRequest("GET", "/admin/schema", nil))
+
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+		}
+	})
+}
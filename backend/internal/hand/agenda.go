@@ -0,0 +1,230 @@
+package hand
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+)
+
+// agenda описывает задачи, разложенные по близости срока выполнения.
+// Каждая корзина всегда кодируется как массив, даже если она пуста.
+type agenda struct {
+	Overdue  []models.Task `json:"overdue" xml:"overdue>task"`
+	Today    []models.Task `json:"today" xml:"today>task"`
+	Tomorrow []models.Task `json:"tomorrow" xml:"tomorrow>task"`
+	ThisWeek []models.Task `json:"this_week" xml:"this_week>task"`
+	Later    []models.Task `json:"later" xml:"later>task"`
+	Someday  []models.Task `json:"someday" xml:"someday>task"`
+}
+
+// GetAgenda обрабатывает запрос на получение невыполненных задач, сгруппированных по сроку выполнения:
+// просроченные, на сегодня, на завтра, до конца недели, позже и без срока.
+// Границы дней вычисляются в часовом поясе из параметра tz или в часовом поясе по умолчанию.
+func (h *taskHandler) GetAgenda(w http.ResponseWriter, r *http.Request) {
+	// Определяем часовой пояс, в котором считаются границы дней
+	location, err := h.requestLocation(r)
+	if err != nil {
+		// Возвращаем ошибку при неизвестном часовом поясе
+		writeError(w, r, "Invalid tz parameter", http.StatusBadRequest)
+		return
+	}
+
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Выполняем запрос на выборку невыполненных задач, упорядоченных по сроку выполнения
+	query := "SELECT " + taskColumns + " FROM tasks WHERE status <> $1 AND user_id = $2 AND deleted_at IS NULL ORDER BY due_date NULLS LAST, id"
+	userID := currentUserID(r)
+	rows, err := h.db.Query(ctx, query, models.StatusDone, userID)
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, models.StatusDone, userID)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	defer rows.Close()
+
+	// Вычисляем границы корзин относительно текущего момента
+	now := time.Now().In(location)
+	bounds := newAgendaBounds(now)
+
+	result := agenda{
+		Overdue:  []models.Task{},
+		Today:    []models.Task{},
+		Tomorrow: []models.Task{},
+		ThisWeek: []models.Task{},
+		Later:    []models.Task{},
+		Someday:  []models.Task{},
+	}
+	// Итерируем по результатам выборки и раскладываем задачи по корзинам
+	for rows.Next() {
+		var task models.Task
+		if err := scanTask(rows, &task); err != nil {
+			// Возвращаем ошибку сервера при сбое сканирования
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+
+		// Задачи без срока выполнения попадают в отдельную корзину
+		if task.DueDate == "" {
+			result.Someday = append(result.Someday, task)
+			continue
+		}
+		dueDate, err := time.Parse(time.RFC3339Nano, task.DueDate)
+		if err != nil {
+			// Возвращаем ошибку сервера, если срок выполнения не удалось разобрать
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+
+		switch {
+		case dueDate.Before(now):
+			result.Overdue = append(result.Overdue, task)
+		case dueDate.Before(bounds.tomorrow):
+			result.Today = append(result.Today, task)
+		case dueDate.Before(bounds.dayAfterTomorrow):
+			result.Tomorrow = append(result.Tomorrow, task)
+		case dueDate.Before(bounds.nextWeek):
+			result.ThisWeek = append(result.ThisWeek, task)
+		default:
+			result.Later = append(result.Later, task)
+		}
+	}
+
+	// Возвращаем сгруппированные задачи в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, result)
+}
+
+// GetInbox обрабатывает запрос на получение списка невыполненных задач в порядке,
+// удобном для показа по умолчанию: сначала просроченные (самые давние первыми),
+// затем предстоящие по возрастанию срока, затем задачи без срока; при равенстве - по ID.
+// Поддерживает параметры limit и offset с теми же значениями по умолчанию, что и GetTasks.
+func (h *taskHandler) GetInbox(w http.ResponseWriter, r *http.Request) {
+	// Разбираем параметры постраничного вывода
+	page, err := parsePagination(r)
+	if err != nil {
+		// Возвращаем ошибку при некорректных limit или offset
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Сортировка по сроку по возрастанию сама ставит просроченные задачи
+	// перед предстоящими, а NULLS LAST отправляет задачи без срока в конец
+	filter := &taskFilter{}
+	filter.where("status <> " + filter.arg(models.StatusDone))
+	filter.where("user_id = " + filter.arg(currentUserID(r)))
+	filter.where("deleted_at IS NULL")
+	query := "SELECT " + taskColumns + " FROM tasks" + filter.clause() +
+		" ORDER BY due_date ASC NULLS LAST, id LIMIT " + filter.arg(page.limit) + " OFFSET " + filter.arg(page.offset)
+
+	// Выполняем запрос на выборку задач
+	rows, err := h.db.Query(ctx, query, filter.args...)
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, filter.args...)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	// Итерируем по результатам выборки и заполняем срез задач
+	for rows.Next() {
+		var task models.Task
+		if err := scanTask(rows, &task); err != nil {
+			// Возвращаем ошибку сервера при сбое сканирования
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+		tasks = append(tasks, task)
+	}
+
+	// Возвращаем задачи в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, tasks)
+}
+
+// GetCompletedToday обрабатывает запрос на получение задач, выполненных сегодня (например,
+// для отчета на ежедневной встрече). Границы дня вычисляются в часовом поясе из параметра tz
+// или в часовом поясе по умолчанию; задачи упорядочены по времени выполнения, последние первыми.
+func (h *taskHandler) GetCompletedToday(w http.ResponseWriter, r *http.Request) {
+	// Определяем часовой пояс, в котором считаются границы дня
+	location, err := h.requestLocation(r)
+	if err != nil {
+		// Возвращаем ошибку при неизвестном часовом поясе
+		writeError(w, r, "Invalid tz parameter", http.StatusBadRequest)
+		return
+	}
+
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Метки времени хранятся в UTC, поэтому границы дня передаются в UTC
+	now := time.Now().In(location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	filter := &taskFilter{}
+	filter.where("status = " + filter.arg(models.StatusDone))
+	filter.where("completed_at >= " + filter.arg(today.UTC()))
+	filter.where("completed_at < " + filter.arg(newAgendaBounds(now).tomorrow.UTC()))
+	filter.where("user_id = " + filter.arg(currentUserID(r)))
+	filter.where("deleted_at IS NULL")
+	query := "SELECT " + taskColumns + " FROM tasks" + filter.clause() + " ORDER BY completed_at DESC, id DESC"
+
+	// Выполняем запрос на выборку задач
+	rows, err := h.db.Query(ctx, query, filter.args...)
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, filter.args...)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	// Итерируем по результатам выборки и заполняем срез задач
+	for rows.Next() {
+		var task models.Task
+		if err := scanTask(rows, &task); err != nil {
+			// Возвращаем ошибку сервера при сбое сканирования
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+		tasks = append(tasks, task)
+	}
+
+	// Возвращаем задачи в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, tasks)
+}
+
+// agendaBounds содержит начала дней, разделяющие корзины повестки.
+type agendaBounds struct {
+	tomorrow         time.Time
+	dayAfterTomorrow time.Time
+	nextWeek         time.Time
+}
+
+// newAgendaBounds вычисляет границы корзин для момента now в его часовом поясе.
+// Неделя считается начинающейся с понедельника.
+func newAgendaBounds(now time.Time) agendaBounds {
+	// Полночь текущего дня в часовом поясе now
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	// Количество дней до следующего понедельника (для понедельника - 7)
+	daysToMonday := (8 - int(today.Weekday())) % 7
+	if daysToMonday == 0 {
+		daysToMonday = 7
+	}
+
+	return agendaBounds{
+		tomorrow:         today.AddDate(0, 0, 1),
+		dayAfterTomorrow: today.AddDate(0, 0, 2),
+		nextWeek:         today.AddDate(0, 0, daysToMonday),
+	}
+}
@@ -0,0 +1,142 @@
+package hand
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/auth"
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// maxAPIKeyNameLength - максимальная длина названия API-ключа в символах.
+const maxAPIKeyNameLength = 255
+
+// CreateAPIKey обрабатывает запрос на создание API-ключа аутентифицированного пользователя.
+// Принимает необязательное название {"name": "..."}. Возвращает созданный ключ в формате
+// JSON; сам ключ присутствует только в этом ответе и позже не может быть получен.
+func (h *userHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var apiKey models.APIKey
+	// Декодируем JSON-запрос в структуру apiKey; пустое тело допустимо
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&apiKey); err != nil {
+			// Возвращаем ошибку при некорректном запросе
+			writePayloadError(w, r, err)
+			return
+		}
+	}
+	if len([]rune(apiKey.Name)) > maxAPIKeyNameLength {
+		writeError(w, r, "name must be at most 255 characters", http.StatusBadRequest)
+		return
+	}
+
+	// Генерируем ключ; сохраняем только его хеш и начало для отображения
+	key, err := auth.GenerateAPIKey()
+	if err != nil {
+		h.log(r.Context()).Error("Failed to generate API key", "error", err)
+		writeError(w, r, "Error creating API key", http.StatusInternalServerError)
+		return
+	}
+	apiKey.Key = key
+	apiKey.Prefix = key[:auth.APIKeyDisplayLength]
+	apiKey.CreatedAt = time.Now().Format(time.RFC3339)
+	apiKey.RevokedAt = ""
+
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Выполняем запрос на вставку ключа и получаем его ID; сам ключ не передается в лог
+	userID := currentUserID(r)
+	query := "INSERT INTO api_keys (user_id, name, prefix, key_hash, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id"
+	err = h.db.QueryRow(ctx, query, userID, apiKey.Name, apiKey.Prefix, auth.HashAPIKey(key), apiKey.CreatedAt).Scan(&apiKey.ID)
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера, если вставка не удалась
+		h.logQueryError(ctx, err, query, userID, apiKey.Name, apiKey.Prefix)
+		writeServerError(ctx, w, r, err, "Error creating API key")
+		return
+	}
+
+	// Устанавливаем статус ответа как Created и возвращаем созданный ключ
+	writeResponse(h.log(r.Context()), w, r, http.StatusCreated, apiKey)
+}
+
+// GetAPIKeys обрабатывает запрос на получение API-ключей аутентифицированного пользователя,
+// включая отозванные. Сами ключи не возвращаются, только их начало.
+func (h *userHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Выполняем запрос на выборку ключей пользователя
+	userID := currentUserID(r)
+	query := "SELECT id, name, prefix, created_at, revoked_at FROM api_keys WHERE user_id=$1 ORDER BY id"
+	rows, err := h.db.Query(ctx, query, userID)
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, userID)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	defer rows.Close()
+
+	apiKeys := []models.APIKey{}
+	// Итерируем по результатам выборки и заполняем срез ключей
+	for rows.Next() {
+		var apiKey models.APIKey
+		var revokedAt sql.NullString
+		if err := rows.Scan(&apiKey.ID, &apiKey.Name, &apiKey.Prefix, &apiKey.CreatedAt, &revokedAt); err != nil {
+			// Возвращаем ошибку сервера при сбое сканирования
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+		apiKey.RevokedAt = revokedAt.String
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	// Возвращаем ключи в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, apiKeys)
+}
+
+// RevokeAPIKey обрабатывает запрос на отзыв API-ключа аутентифицированного пользователя по ID.
+// Отозванный ключ сразу перестает приниматься. Возвращает 404, если действующего ключа
+// с таким ID у пользователя нет.
+func (h *userHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Извлекаем ID ключа из параметров запроса
+	vars := mux.Vars(r)
+	keyID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		// Возвращаем ошибку при некорректном ID
+		writeError(w, r, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+
+	// Помечаем ключ отозванным
+	var revokedID int
+	query := "UPDATE api_keys SET revoked_at=$1 WHERE id=$2 AND user_id=$3 AND revoked_at IS NULL RETURNING id"
+	args := []interface{}{time.Now().Format(time.RFC3339), keyID, currentUserID(r)}
+	err = h.db.QueryRow(ctx, query, args...).Scan(&revokedID)
+	if err == sql.ErrNoRows {
+		// Возвращаем ошибку, если действующий ключ не найден
+		writeError(w, r, "API key not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое обновления
+		h.logQueryError(ctx, err, query, args...)
+		writeServerError(ctx, w, r, err, "Error revoking API key")
+		return
+	}
+
+	// Устанавливаем статус ответа как No Content (204) при успешном отзыве
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,182 @@
+package hand
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/database"
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// auditChange - прежнее и новое значение поля задачи в журнале изменений.
+type auditChange struct {
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+// insertAuditQuery добавляет запись в журнал изменений задачи. Запись должна
+// выполняться в той же транзакции, что и само изменение, чтобы журнал
+// не расходился с данными.
+const insertAuditQuery = "INSERT INTO task_audit (task_id, user_id, action, changes, created_at) VALUES ($1, $2, $3, $4, $5)"
+
+// auditArgs возвращает аргументы insertAuditQuery. Нулевой userID (изменение,
+// сделанное самим сервисом) и пустой набор изменений сохраняются как NULL.
+func auditArgs(userID, taskID int, action string, changes map[string]auditChange) []interface{} {
+	var user, data interface{}
+	if userID != 0 {
+		user = userID
+	}
+	if len(changes) > 0 {
+		// Значения полей задачи всегда кодируются в JSON, поэтому ошибку можно не проверять
+		encoded, _ := json.Marshal(changes)
+		data = encoded
+	}
+	return []interface{}{taskID, user, action, data, time.Now().Format(time.RFC3339)}
+}
+
+// auditDiff возвращает поля задачи, изменившиеся между состояниями before и after,
+// с прежними и новыми значениями. Для созданной задачи (before равен nil)
+// возвращаются только новые значения заполненных полей. Служебные поля, которые
+// меняются при любом изменении (время изменения и версия), в журнал не попадают:
+// время изменения записывается в саму запись журнала.
+func auditDiff(before *models.Task, after models.Task) map[string]auditChange {
+	previous := models.Task{ID: after.ID}
+	if before != nil {
+		previous = *before
+	}
+	newValues := changedFields(previous, after)
+	oldValues := changedFields(after, previous)
+
+	diff := make(map[string]auditChange, len(newValues))
+	for field, value := range newValues {
+		if field == "id" || field == "updated_at" || field == "version" {
+			continue
+		}
+		change := auditChange{New: value}
+		if before != nil {
+			change.Old = oldValues[field]
+		}
+		diff[field] = change
+	}
+	return diff
+}
+
+// audit записывает изменение задачи taskID пользователем userID в журнал
+// в транзакции q.
+func (h *taskHandler) audit(ctx context.Context, q database.Querier, userID, taskID int, action string, changes map[string]auditChange) error {
+	args := auditArgs(userID, taskID, action, changes)
+	if _, err := q.Exec(ctx, insertAuditQuery, args...); err != nil {
+		h.logQueryError(ctx, err, insertAuditQuery, args...)
+		return err
+	}
+	return nil
+}
+
+// lockTask выбирает неудаленную задачу taskID пользователя userID и блокирует ее
+// строку до конца транзакции q, чтобы прежнее состояние для журнала не изменилось
+// до записи нового. Возвращает sql.ErrNoRows, если задачи нет.
+func (h *taskHandler) lockTask(ctx context.Context, q database.Querier, userID, taskID int) (models.Task, error) {
+	var task models.Task
+	query := "SELECT " + taskColumns + " FROM tasks WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL FOR UPDATE"
+	err := scanTask(q.QueryRow(ctx, query, taskID, userID), &task)
+	if err != nil && err != sql.ErrNoRows {
+		h.logQueryError(ctx, err, query, taskID, userID)
+	}
+	return task, err
+}
+
+// updateTaskAudited выполняет в транзакции запрос query, изменяющий задачу taskID
+// и возвращающий ее колонки taskColumns, и записывает изменившиеся поля в журнал.
+// Возвращает sql.ErrNoRows, если задачи нет или она удалена.
+func (h *taskHandler) updateTaskAudited(ctx context.Context, userID, taskID int, query string, args []interface{}) (models.Task, error) {
+	var task models.Task
+	err := h.withTx(ctx, func(tx database.Tx) error {
+		before, err := h.lockTask(ctx, tx, userID, taskID)
+		if err != nil {
+			return err
+		}
+		if err := scanTask(tx.QueryRow(ctx, query, args...), &task); err != nil {
+			if err != sql.ErrNoRows {
+				h.logQueryError(ctx, err, query, args...)
+			}
+			return err
+		}
+		return h.audit(ctx, tx, userID, taskID, models.AuditUpdated, auditDiff(&before, task))
+	})
+	return task, err
+}
+
+// GetTaskHistory обрабатывает запрос на получение журнала изменений задачи с указанным ID.
+// Журнал доступен и для удаленной задачи. Возвращает записи в порядке изменений
+// в формате JSON или 404, если задачи нет.
+func (h *taskHandler) GetTaskHistory(w http.ResponseWriter, r *http.Request) {
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Извлекаем ID задачи из параметров запроса
+	vars := mux.Vars(r)
+	taskID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		// Возвращаем ошибку при некорректном ID
+		writeError(w, r, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	// Проверяем, что задача принадлежит пользователю; удаленные задачи тоже подходят
+	var exists int
+	userID := currentUserID(r)
+	query := "SELECT id FROM tasks WHERE id=$1 AND user_id=$2"
+	err = h.db.QueryRow(ctx, query, taskID, userID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		// Возвращаем ошибку, если задача не найдена
+		writeError(w, r, "Task not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, taskID, userID)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+
+	// Выбираем записи журнала в порядке изменений
+	query = "SELECT id, task_id, user_id, action, changes, created_at FROM task_audit WHERE task_id=$1 ORDER BY id"
+	rows, err := h.db.Query(ctx, query, taskID)
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, taskID)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	defer rows.Close()
+
+	entries := []models.TaskAuditEntry{}
+	for rows.Next() {
+		var entry models.TaskAuditEntry
+		var user sql.NullInt64
+		var changes []byte
+		if err := rows.Scan(&entry.ID, &entry.TaskID, &user, &entry.Action, &changes, &entry.CreatedAt); err != nil {
+			// Возвращаем ошибку сервера при сбое сканирования
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+		entry.UserID = int(user.Int64)
+		entry.Changes = changes
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		h.logQueryError(ctx, err, query, taskID)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+
+	// Возвращаем журнал изменений в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, entries)
+}
@@ -0,0 +1,88 @@
+package hand
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// blockRequest описывает тело запроса на блокировку задачи.
+type blockRequest struct {
+	Reason string `json:"reason"`
+}
+
+// BlockTask обрабатывает запрос на блокировку задачи по её ID.
+// Необязательное тело {"reason": "..."} задает причину блокировки.
+// Возвращает обновленную задачу в формате JSON или 404, если задача не найдена.
+func (h *taskHandler) BlockTask(w http.ResponseWriter, r *http.Request) {
+	var req blockRequest
+	// Декодируем JSON-запрос; пустое тело означает блокировку без причины
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		// Возвращаем ошибку при некорректном запросе
+		writePayloadError(w, r, err)
+		return
+	}
+
+	// Проверяем длину причины блокировки
+	if len([]rune(req.Reason)) > models.MaxBlockedReasonLength {
+		writeError(w, r, fmt.Sprintf("reason must be at most %d characters", models.MaxBlockedReasonLength), http.StatusBadRequest)
+		return
+	}
+
+	var reason interface{}
+	if req.Reason != "" {
+		reason = req.Reason
+	}
+	h.setBlocked(w, r, true, reason)
+}
+
+// UnblockTask обрабатывает запрос на снятие блокировки задачи по её ID.
+// Возвращает обновленную задачу в формате JSON или 404, если задача не найдена.
+func (h *taskHandler) UnblockTask(w http.ResponseWriter, r *http.Request) {
+	h.setBlocked(w, r, false, nil)
+}
+
+// setBlocked устанавливает признак и причину блокировки задачи из пути запроса
+// и возвращает обновленную задачу в формате JSON.
+func (h *taskHandler) setBlocked(w http.ResponseWriter, r *http.Request, blocked bool, reason interface{}) {
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Извлекаем ID задачи из параметров запроса
+	vars := mux.Vars(r)
+	taskID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		// Возвращаем ошибку при некорректном ID
+		writeError(w, r, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	// Обновляем признак блокировки, записываем изменение в журнал и получаем обновленную задачу
+	var task models.Task
+	query := "UPDATE tasks SET blocked=$1, blocked_reason=$2, updated_at=$3, version=version+1 WHERE id=$4 AND user_id=$5 AND deleted_at IS NULL RETURNING " + taskColumns
+	args := []interface{}{blocked, reason, time.Now().Format(time.RFC3339), taskID, currentUserID(r)}
+	task, err = h.updateTaskAudited(ctx, currentUserID(r), taskID, query, args)
+	if err == sql.ErrNoRows {
+		// Возвращаем ошибку, если задача не найдена
+		writeError(w, r, "Task not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		// Возвращаем ошибку сервера при сбое обновления
+		writeServerError(ctx, w, r, err, "Error updating task")
+		return
+	}
+
+	// Возвращаем обновленную задачу в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, task)
+}
@@ -0,0 +1,229 @@
+package hand
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/database"
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+)
+
+// maxBulkTasks - максимальное количество задач в одном запросе массового создания.
+const maxBulkTasks = 1000
+
+// bulkQueryTimeout - минимальный таймаут массового создания: проверка и вставка
+// до maxBulkTasks задач выполняются дольше, чем операции с одной задачей.
+const bulkQueryTimeout = 30 * time.Second
+
+// bulkTaskError описывает ошибки проверки одной задачи из массового запроса.
+type bulkTaskError struct {
+	Index  int                     `json:"index" xml:"index"`
+	Errors models.ValidationErrors `json:"errors" xml:"errors"`
+}
+
+// bulkErrorBody - ответ 400 на массовый запрос с ошибками проверки задач.
+type bulkErrorBody struct {
+	Errors []bulkTaskError `json:"errors" xml:"errors>task"`
+}
+
+// createdTask - задача, созданная массовым запросом, вместе с ее адресом,
+// который при создании одной задачи передается в заголовке Location.
+type createdTask struct {
+	models.Task
+	Location string `json:"location" xml:"location"`
+}
+
+// newCreatedTask возвращает созданную задачу task с ее адресом.
+func newCreatedTask(task models.Task) *createdTask {
+	return &createdTask{Task: task, Location: taskLocation(task.ID)}
+}
+
+// bulkTaskResult - итог создания одной задачи в режиме partial=true: созданная задача
+// либо ошибки проверки или сообщение об ошибке вставки. Status - код, которым
+// завершился бы запрос на создание этой задачи по отдельности.
+type bulkTaskResult struct {
+	Index  int                     `json:"index" xml:"index"`
+	Status int                     `json:"status" xml:"status"`
+	Task   *createdTask            `json:"task,omitempty" xml:"task,omitempty"`
+	Errors models.ValidationErrors `json:"errors,omitempty" xml:"errors,omitempty"`
+	Error  string                  `json:"error,omitempty" xml:"error,omitempty"`
+}
+
+// bulkResultBody - ответ 207 на массовый запрос в режиме partial=true.
+type bulkResultBody struct {
+	Results []bulkTaskResult `json:"results" xml:"results>result"`
+}
+
+// CreateTasksBulk обрабатывает запрос на массовое создание задач.
+// Принимает JSON-массив задач, проверяет каждую и вставляет все задачи в одной транзакции.
+// Если хотя бы одна задача некорректна, ни одна задача не создается, а ответ 400
+// содержит индекс и ошибки каждой некорректной задачи. При успехе возвращает
+// массив созданных задач с присвоенными ID и адресами (поле location).
+//
+// С параметром partial=true каждая задача проверяется и вставляется в отдельной
+// транзакции: некорректные задачи не мешают создать остальные, а ответ 207
+// содержит итог каждой задачи (см. bulkTaskResult).
+func (h *taskHandler) CreateTasksBulk(w http.ResponseWriter, r *http.Request) {
+	// Определяем режим: по умолчанию создаются все задачи или ни одна
+	partial := false
+	if value := r.URL.Query().Get("partial"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			// Возвращаем ошибку при некорректном значении флага
+			writeError(w, r, "Invalid partial parameter", http.StatusBadRequest)
+			return
+		}
+		partial = parsed
+	}
+
+	var tasks []models.Task
+	// Декодируем JSON-запрос в срез задач, отклоняя неизвестные поля
+	if err := decodeStrict(r.Body, &tasks); err != nil {
+		// Возвращаем ошибку при некорректном запросе
+		writePayloadError(w, r, err)
+		return
+	}
+	if len(tasks) == 0 {
+		writeError(w, r, "Request must contain at least one task", http.StatusBadRequest)
+		return
+	}
+	if len(tasks) > maxBulkTasks {
+		writeError(w, r, fmt.Sprintf("Request must contain at most %d tasks", maxBulkTasks), http.StatusBadRequest)
+		return
+	}
+
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), max(h.queryTimeout, bulkQueryTimeout))
+	defer cancel()
+
+	if partial {
+		h.createTasksPartial(ctx, w, r, tasks)
+		return
+	}
+
+	// Проверяем все задачи, чтобы вернуть клиенту сразу все ошибки
+	userID := currentUserID(r)
+	var failures []bulkTaskError
+	for i := range tasks {
+		errs, err := h.checkBulkTask(ctx, userID, &tasks[i])
+		if err != nil {
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+		if len(errs) > 0 {
+			failures = append(failures, bulkTaskError{Index: i, Errors: errs})
+		}
+	}
+	if len(failures) > 0 {
+		writeResponse(h.log(r.Context()), w, r, http.StatusBadRequest, bulkErrorBody{Errors: failures})
+		return
+	}
+
+	// Вставляем все задачи, их зависимости и метки в одной транзакции:
+	// либо создаются все задачи, либо ни одна
+	now := time.Now().Format(time.RFC3339)
+	err := h.withTx(ctx, func(tx database.Tx) error {
+		for i := range tasks {
+			setCreationTime(&tasks[i], now)
+			if err := h.insertBulkTask(ctx, tx, &tasks[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// Возвращаем ошибку сервера, если вставка не удалась
+		writeServerError(ctx, w, r, err, "Error creating tasks")
+		return
+	}
+
+	// Устанавливаем статус ответа как Created и возвращаем созданные задачи с их адресами
+	created := make([]*createdTask, len(tasks))
+	for i := range tasks {
+		created[i] = newCreatedTask(tasks[i])
+	}
+	writeResponse(h.log(r.Context()), w, r, http.StatusCreated, created)
+}
+
+// createTasksPartial создает задачи tasks независимо друг от друга: каждая корректная
+// задача вставляется в своей транзакции, а ответ 207 перечисляет итог каждой задачи
+// в порядке запроса.
+func (h *taskHandler) createTasksPartial(ctx context.Context, w http.ResponseWriter, r *http.Request, tasks []models.Task) {
+	userID := currentUserID(r)
+	now := time.Now().Format(time.RFC3339)
+	results := make([]bulkTaskResult, len(tasks))
+	for i := range tasks {
+		results[i].Index = i
+		errs, err := h.checkBulkTask(ctx, userID, &tasks[i])
+		if err == nil && len(errs) == 0 {
+			setCreationTime(&tasks[i], now)
+			err = h.withTx(ctx, func(tx database.Tx) error {
+				return h.insertBulkTask(ctx, tx, &tasks[i])
+			})
+		}
+		// Ошибка одной задачи не прерывает создание остальных
+		switch {
+		case err != nil:
+			results[i].Status, results[i].Error = serverError(ctx, err, "Error creating task")
+		case len(errs) > 0:
+			results[i].Status = http.StatusBadRequest
+			results[i].Errors = errs
+		default:
+			results[i].Status = http.StatusCreated
+			results[i].Task = newCreatedTask(tasks[i])
+		}
+	}
+	writeResponse(h.log(r.Context()), w, r, http.StatusMultiStatus, bulkResultBody{Results: results})
+}
+
+// checkBulkTask подготавливает задачу task пользователя userID к вставке и возвращает
+// ее ошибки проверки. Зависимости и родительская задача могут ссылаться только на уже
+// существующие задачи. Ошибка err означает сбой запроса к базе данных.
+func (h *taskHandler) checkBulkTask(ctx context.Context, userID int, task *models.Task) (models.ValidationErrors, error) {
+	task.UserID = userID
+	errs := models.ValidationErrors{}
+	if err := h.prepareNewTask(task); err != nil {
+		errs = err.(models.ValidationErrors)
+	}
+	task.DependsOn = uniqueIDs(task.DependsOn)
+	if err := h.checkDependencies(ctx, userID, 0, task.DependsOn); err != nil {
+		var relErr *relationError
+		if !errors.As(err, &relErr) {
+			return nil, err
+		}
+		errs["depends_on"] = relErr.message
+	}
+	if err := h.checkParent(ctx, userID, 0, task.ParentID); err != nil {
+		var relErr *relationError
+		if !errors.As(err, &relErr) {
+			return nil, err
+		}
+		errs["parent_id"] = relErr.message
+	}
+	return errs, nil
+}
+
+// insertBulkTask вставляет проверенную задачу task, ее зависимости, метки и запись
+// аудита в транзакции tx и заполняет ID, позицию и версию задачи.
+func (h *taskHandler) insertBulkTask(ctx context.Context, tx database.Tx, task *models.Task) error {
+	args := insertTaskArgs(task)
+	if err := tx.QueryRow(ctx, insertTaskQuery, args...).Scan(&task.ID, &task.Position, &task.Version); err != nil {
+		h.logQueryError(ctx, err, insertTaskQuery, args...)
+		return err
+	}
+	if len(task.DependsOn) > 0 {
+		if err := h.saveDependencies(ctx, tx, task.ID, task.DependsOn); err != nil {
+			return err
+		}
+	}
+	if len(task.Tags) > 0 {
+		if err := h.saveTags(ctx, tx, task.UserID, task.ID, task.Tags); err != nil {
+			return err
+		}
+	}
+	return h.audit(ctx, tx, task.UserID, task.ID, models.AuditCreated, auditDiff(nil, *task))
+}
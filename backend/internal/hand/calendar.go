@@ -0,0 +1,158 @@
+package hand
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+)
+
+// icsTimeFormat - формат времени UTC в iCalendar (RFC 5545, раздел 3.3.5).
+const icsTimeFormat = "20060102T150405Z"
+
+// icsMaxLineLength - максимальная длина строки iCalendar в байтах без CRLF;
+// более длинные строки переносятся (RFC 5545, раздел 3.1).
+const icsMaxLineLength = 75
+
+// icsTextEscaper экранирует спецсимволы текстовых значений iCalendar.
+var icsTextEscaper = strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// icsWriter записывает строки iCalendar с переносом длинных строк.
+type icsWriter struct {
+	w strings.Builder
+}
+
+// line записывает свойство name со значением value. Строки длиннее icsMaxLineLength
+// байт переносятся: продолжение начинается с пробела. Перенос не разрывает
+// многобайтовые символы UTF-8.
+func (w *icsWriter) line(name, value string) {
+	line := name + ":" + value
+	limit := icsMaxLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// Пробел в начале продолжения занимает один байт строки
+		limit = icsMaxLineLength - 1
+	}
+	w.w.WriteString(line + "\r\n")
+}
+
+// text записывает свойство name с экранированным текстовым значением value.
+func (w *icsWriter) text(name, value string) {
+	w.line(name, icsTextEscaper.Replace(value))
+}
+
+// icsTime преобразует метку времени RFC3339 в формат iCalendar.
+func icsTime(value string) (string, bool) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return "", false
+	}
+	return t.UTC().Format(icsTimeFormat), true
+}
+
+// event записывает задачу task как событие VEVENT в момент ее срока выполнения.
+func (w *icsWriter) event(task models.Task) {
+	due, ok := icsTime(task.DueDate)
+	if !ok {
+		return
+	}
+	// DTSTAMP обязателен; если время изменения не разобрать, используется срок задачи
+	stamp, ok := icsTime(task.UpdatedAt)
+	if !ok {
+		stamp = due
+	}
+
+	w.line("BEGIN", "VEVENT")
+	// UID должен быть постоянным, чтобы календарь обновлял событие, а не создавал новое
+	w.line("UID", "task-"+strconv.Itoa(task.ID)+"@taskapi")
+	w.line("DTSTAMP", stamp)
+	w.line("DTSTART", due)
+	w.line("DTEND", due)
+	w.text("SUMMARY", task.Title)
+	if task.Description != "" {
+		w.text("DESCRIPTION", task.Description)
+	}
+	if task.Status == models.StatusDone {
+		w.line("STATUS", "CANCELLED")
+	} else {
+		w.line("STATUS", "CONFIRMED")
+	}
+	w.line("END", "VEVENT")
+}
+
+// GetCalendar обрабатывает запрос на получение задач со сроком выполнения в формате
+// iCalendar для подписки из приложений календаря. Каждая задача со сроком выполнения
+// становится событием в момент срока; выполненные задачи помечаются отмененными
+// событиями. Поддерживает те же фильтры, что и GetTasks.
+func (h *taskHandler) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	// Определяем часовой пояс для дат без времени в параметрах фильтрации
+	location, err := h.requestLocation(r)
+	if err != nil {
+		// Возвращаем ошибку при неизвестном часовом поясе
+		writeError(w, r, "Invalid tz parameter", http.StatusBadRequest)
+		return
+	}
+
+	// Разбираем параметры фильтрации так же, как для списка задач;
+	// в календарь попадают только задачи со сроком выполнения
+	filter, err := parseTaskFilter(r, location)
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.where("due_date IS NOT NULL")
+
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), max(h.queryTimeout, exportTimeout))
+	defer cancel()
+
+	// Выполняем запрос на выборку задач со сроком выполнения
+	query := "SELECT " + taskColumns + " FROM tasks" + filter.clause() + " ORDER BY due_date, id"
+	rows, err := h.db.Query(ctx, query, filter.args...)
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, filter.args...)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	defer rows.Close()
+
+	// Собираем календарь целиком до отправки: календарь с оборванным концом
+	// приложения отвергают, а ошибка в середине не должна давать ответ 200
+	ics := &icsWriter{}
+	ics.line("BEGIN", "VCALENDAR")
+	ics.line("VERSION", "2.0")
+	ics.line("PRODID", "-//taskApi//Tasks//EN")
+	ics.line("CALSCALE", "GREGORIAN")
+	ics.line("METHOD", "PUBLISH")
+	ics.text("X-WR-CALNAME", "Tasks")
+
+	for rows.Next() {
+		var task models.Task
+		if err := scanTask(rows, &task); err != nil {
+			// Возвращаем ошибку сервера при сбое сканирования
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+		ics.event(task)
+	}
+	if err := rows.Err(); err != nil {
+		h.logQueryError(ctx, err, query, filter.args...)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	ics.line("END", "VCALENDAR")
+
+	// Возвращаем календарь с типом содержимого iCalendar
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Write([]byte(ics.w.String()))
+}
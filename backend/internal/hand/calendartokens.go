@@ -0,0 +1,140 @@
+package hand
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/auth"
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// CreateCalendarToken обрабатывает запрос на создание токена подписки на календарь
+// аутентифицированного пользователя. Принимает необязательное название {"name": "..."}.
+// Возвращает созданный токен; сам токен присутствует только в этом ответе.
+// Токен дает доступ только к GET /tasks/calendar.ics.
+func (h *userHandler) CreateCalendarToken(w http.ResponseWriter, r *http.Request) {
+	var token models.CalendarToken
+	// Декодируем JSON-запрос в структуру token; пустое тело допустимо
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&token); err != nil {
+			// Возвращаем ошибку при некорректном запросе
+			writePayloadError(w, r, err)
+			return
+		}
+	}
+	if len([]rune(token.Name)) > maxAPIKeyNameLength {
+		writeError(w, r, "name must be at most 255 characters", http.StatusBadRequest)
+		return
+	}
+
+	// Генерируем токен; сохраняем только его хеш и начало для отображения
+	secret, err := auth.GenerateCalendarToken()
+	if err != nil {
+		h.log(r.Context()).Error("Failed to generate calendar token", "error", err)
+		writeError(w, r, "Error creating calendar token", http.StatusInternalServerError)
+		return
+	}
+	token.Token = secret
+	token.Prefix = secret[:auth.CalendarTokenDisplayLength]
+	token.CreatedAt = time.Now().Format(time.RFC3339)
+	token.RevokedAt = ""
+
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Выполняем запрос на вставку токена и получаем его ID; сам токен не передается в лог
+	userID := currentUserID(r)
+	query := "INSERT INTO calendar_tokens (user_id, name, prefix, token_hash, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id"
+	err = h.db.QueryRow(ctx, query, userID, token.Name, token.Prefix, auth.HashAPIKey(secret), token.CreatedAt).Scan(&token.ID)
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера, если вставка не удалась
+		h.logQueryError(ctx, err, query, userID, token.Name, token.Prefix)
+		writeServerError(ctx, w, r, err, "Error creating calendar token")
+		return
+	}
+
+	// Устанавливаем статус ответа как Created и возвращаем созданный токен
+	writeResponse(h.log(r.Context()), w, r, http.StatusCreated, token)
+}
+
+// GetCalendarTokens обрабатывает запрос на получение токенов календаря аутентифицированного
+// пользователя, включая отозванные. Сами токены не возвращаются, только их начало.
+func (h *userHandler) GetCalendarTokens(w http.ResponseWriter, r *http.Request) {
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Выполняем запрос на выборку токенов пользователя
+	userID := currentUserID(r)
+	query := "SELECT id, name, prefix, created_at, revoked_at FROM calendar_tokens WHERE user_id=$1 ORDER BY id"
+	rows, err := h.db.Query(ctx, query, userID)
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, userID)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	defer rows.Close()
+
+	tokens := []models.CalendarToken{}
+	// Итерируем по результатам выборки и заполняем срез токенов
+	for rows.Next() {
+		var token models.CalendarToken
+		var revokedAt sql.NullString
+		if err := rows.Scan(&token.ID, &token.Name, &token.Prefix, &token.CreatedAt, &revokedAt); err != nil {
+			// Возвращаем ошибку сервера при сбое сканирования
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+		token.RevokedAt = revokedAt.String
+		tokens = append(tokens, token)
+	}
+
+	// Возвращаем токены в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, tokens)
+}
+
+// RevokeCalendarToken обрабатывает запрос на отзыв токена календаря аутентифицированного
+// пользователя по ID. Отозванный токен сразу перестает приниматься. Возвращает 404,
+// если действующего токена с таким ID у пользователя нет.
+func (h *userHandler) RevokeCalendarToken(w http.ResponseWriter, r *http.Request) {
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Извлекаем ID токена из параметров запроса
+	vars := mux.Vars(r)
+	tokenID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		// Возвращаем ошибку при некорректном ID
+		writeError(w, r, "Invalid calendar token ID", http.StatusBadRequest)
+		return
+	}
+
+	// Помечаем токен отозванным
+	var revokedID int
+	query := "UPDATE calendar_tokens SET revoked_at=$1 WHERE id=$2 AND user_id=$3 AND revoked_at IS NULL RETURNING id"
+	args := []interface{}{time.Now().Format(time.RFC3339), tokenID, currentUserID(r)}
+	err = h.db.QueryRow(ctx, query, args...).Scan(&revokedID)
+	if err == sql.ErrNoRows {
+		// Возвращаем ошибку, если действующий токен не найден
+		writeError(w, r, "Calendar token not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое обновления
+		h.logQueryError(ctx, err, query, args...)
+		writeServerError(ctx, w, r, err, "Error revoking calendar token")
+		return
+	}
+
+	// Устанавливаем статус ответа как No Content (204) при успешном отзыве
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,48 @@
+package hand
+
+import (
+	"net/http"
+
+	"github.com/NickolaiP/taskApi/backend/internal/config"
+	"github.com/NickolaiP/taskApi/backend/internal/logger"
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+)
+
+// clientConfig описывает несекретные настройки сервера, которые нужны клиентам
+// для настройки интерфейса и валидации. Значения берутся из тех же констант
+// и настроек, что используют обработчики, поэтому не расходятся с ними.
+type clientConfig struct {
+	DefaultPageSize      int      `json:"default_page_size" xml:"default_page_size"`
+	MaxPageSize          int      `json:"max_page_size" xml:"max_page_size"`
+	MaxTitleLength       int      `json:"max_title_length" xml:"max_title_length"`
+	AllowedStatuses      []string `json:"allowed_statuses" xml:"allowed_statuses>status"`
+	AllowedPriorities    []string `json:"allowed_priorities" xml:"allowed_priorities>priority"`
+	DefaultPriority      string   `json:"default_priority" xml:"default_priority"`
+	DefaultTimezone      string   `json:"default_timezone" xml:"default_timezone"`
+	TitleFromDescription bool     `json:"title_from_description" xml:"title_from_description"`
+}
+
+// configHandler представляет собой структуру обработчика для выдачи настроек клиентам.
+type configHandler struct {
+	cfg    config.AppConfig
+	logger *logger.Logger
+}
+
+// NewConfigHandler создает новый экземпляр configHandler с заданными настройками приложения и логгером.
+func NewConfigHandler(cfg config.AppConfig, logger *logger.Logger) *configHandler {
+	return &configHandler{cfg: cfg, logger: logger}
+}
+
+// GetConfig обрабатывает запрос на получение несекретных настроек сервера в формате JSON.
+func (h *configHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	writeResponse(logger.FromContext(r.Context(), h.logger), w, r, http.StatusOK, clientConfig{
+		DefaultPageSize:      defaultPageLimit,
+		MaxPageSize:          maxPageLimit,
+		MaxTitleLength:       models.MaxTitleLength,
+		AllowedStatuses:      models.Statuses,
+		AllowedPriorities:    models.Priorities,
+		DefaultPriority:      models.PriorityMedium,
+		DefaultTimezone:      h.cfg.DefaultTimezone,
+		TitleFromDescription: h.cfg.TitleFromDescription,
+	})
+}
@@ -0,0 +1,74 @@
+package hand
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+)
+
+// taskCount - ответ на запрос количества задач: общее количество и количество
+// по каждому статусу. Статусы без задач выводятся с нулем.
+type taskCount struct {
+	Total    int           `json:"total" xml:"total"`
+	ByStatus fieldMap[int] `json:"by_status" xml:"by_status"`
+}
+
+// GetTaskCount обрабатывает запрос на подсчет задач по статусам. Поддерживает
+// те же фильтры, что и GetTasks, поэтому общее количество совпадает с заголовком
+// X-Total-Count списка задач, но задачи не выбираются из базы данных.
+func (h *taskHandler) GetTaskCount(w http.ResponseWriter, r *http.Request) {
+	// Определяем часовой пояс для дат без времени в параметрах фильтрации
+	location, err := h.requestLocation(r)
+	if err != nil {
+		// Возвращаем ошибку при неизвестном часовом поясе
+		writeError(w, r, "Invalid tz parameter", http.StatusBadRequest)
+		return
+	}
+
+	// Разбираем параметры фильтрации так же, как для списка задач
+	filter, err := parseTaskFilter(r, location)
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Считаем задачи по статусам одним запросом
+	query := "SELECT status, COUNT(*) FROM tasks" + filter.clause() + " GROUP BY status"
+	rows, err := h.db.Query(ctx, query, filter.args...)
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, filter.args...)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	defer rows.Close()
+
+	count := taskCount{ByStatus: make(fieldMap[int], len(models.Statuses))}
+	for _, status := range models.Statuses {
+		count.ByStatus[status] = 0
+	}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			// Возвращаем ошибку сервера при сбое сканирования
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+		count.ByStatus[status] = n
+		count.Total += n
+	}
+	if err := rows.Err(); err != nil {
+		h.logQueryError(ctx, err, query, filter.args...)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+
+	// Возвращаем количество задач в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, count)
+}
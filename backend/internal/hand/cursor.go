@@ -0,0 +1,116 @@
+package hand
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+)
+
+// taskSortKey описывает ключ сортировки списка задач перед завершающим ID:
+// SQL-выражение и его значение для задачи. Для сортировки по ID ключ пустой.
+type taskSortKey struct {
+	expr  string
+	value func(task models.Task) float64
+}
+
+// taskSortKeys сопоставляет значения параметра sort с ключами сортировки,
+// по которым курсор продолжает список. Должна соответствовать taskSortOrders.
+var taskSortKeys = map[string]taskSortKey{
+	"id": {},
+	"position": {"position", func(task models.Task) float64 {
+		return task.Position
+	}},
+	"priority": {priorityRank, func(task models.Task) float64 {
+		switch task.Priority {
+		case models.PriorityHigh:
+			return 0
+		case models.PriorityMedium:
+			return 1
+		}
+		return 2
+	}},
+}
+
+// taskCursor - позиция в списке задач: последняя задача предыдущей страницы
+// (ключ сортировки и ID) и порядок сортировки, для которого курсор выдан.
+type taskCursor struct {
+	Sort string  `json:"s"`
+	Key  float64 `json:"k,omitempty"`
+	ID   int     `json:"id"`
+}
+
+// errInvalidCursor возвращается, если параметр cursor не был выдан сервером.
+var errInvalidCursor = errors.New("invalid cursor")
+
+// taskSortName возвращает значение параметра sort списка задач (id по умолчанию)
+// или пустую строку, если задачи упорядочены по релевантности полнотекстового
+// поиска: релевантность не является колонкой, поэтому курсоры для нее не выдаются.
+func taskSortName(r *http.Request, filter *taskFilter) string {
+	name := r.URL.Query().Get("sort")
+	if name == "" {
+		if filter.relevance != "" {
+			return ""
+		}
+		return "id"
+	}
+	return name
+}
+
+// nextTaskCursor возвращает курсор страницы, следующей за задачей last,
+// для порядка сортировки sortName.
+func nextTaskCursor(sortName string, last models.Task) string {
+	cursor := taskCursor{Sort: sortName, ID: last.ID}
+	if key := taskSortKeys[sortName]; key.value != nil {
+		cursor.Key = key.value(last)
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// parseTaskCursor разбирает параметр cursor запроса списка задач для порядка
+// сортировки sortName. Без параметра возвращает nil. Курсор несовместим
+// с параметром offset и действителен только для того порядка сортировки,
+// для которого он выдан.
+func parseTaskCursor(r *http.Request, sortName string) (*taskCursor, error) {
+	query := r.URL.Query()
+	value := query.Get("cursor")
+	if value == "" {
+		return nil, nil
+	}
+	if query.Get("offset") != "" {
+		return nil, errors.New("cursor and offset cannot be used together")
+	}
+	if sortName == "" {
+		return nil, errors.New("cursor is not supported for full-text relevance order")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var cursor taskCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID <= 0 {
+		return nil, errInvalidCursor
+	}
+	if _, ok := taskSortKeys[cursor.Sort]; !ok {
+		return nil, errInvalidCursor
+	}
+	if cursor.Sort != sortName {
+		return nil, errors.New("cursor was issued for a different sort order")
+	}
+	return &cursor, nil
+}
+
+// condition добавляет в фильтр аргументы курсора и возвращает условие выбора
+// задач, следующих за курсором в порядке сортировки: сравнение пар (ключ, ID)
+// продолжает список с места остановки, даже если перед ним появились новые задачи.
+func (c *taskCursor) condition(filter *taskFilter) string {
+	key := taskSortKeys[c.Sort]
+	if key.expr == "" {
+		return "id > " + filter.arg(c.ID)
+	}
+	return "(" + key.expr + ", id) > (" + filter.arg(c.Key) + ", " + filter.arg(c.ID) + ")"
+}
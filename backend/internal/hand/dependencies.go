@@ -0,0 +1,400 @@
+package hand
+
+import (
+	"container/heap"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/NickolaiP/taskApi/backend/internal/database"
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// relationError описывает ошибку проверки связей задачи (зависимостей или
+// родительской задачи), вызванную данными клиента, вместе с HTTP-статусом ответа.
+type relationError struct {
+	status  int
+	message string
+}
+
+func (e *relationError) Error() string {
+	return e.message
+}
+
+// uniqueIDs возвращает отсортированный список идентификаторов без повторов.
+// nil сохраняется как nil, чтобы отличать отсутствующее поле от пустого списка.
+func uniqueIDs(ids []int) []int {
+	if ids == nil {
+		return nil
+	}
+	seen := make(map[int]bool, len(ids))
+	unique := []int{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	sort.Ints(unique)
+	return unique
+}
+
+// checkDependencies проверяет, что задачи из dependsOn существуют и принадлежат
+// пользователю userID, а также что зависимость
+// задачи taskID от них не образует цикл. Для новой задачи taskID равен 0:
+// от нее еще ничего не зависит, поэтому цикл невозможен.
+// Ошибки данных клиента возвращаются как *relationError, остальные - ошибки базы данных.
+func (h *taskHandler) checkDependencies(ctx context.Context, userID, taskID int, dependsOn []int) error {
+	if len(dependsOn) == 0 {
+		return nil
+	}
+
+	// Зависимость от самой себя - простейший цикл
+	for _, id := range dependsOn {
+		if id == taskID {
+			return &relationError{http.StatusConflict, fmt.Sprintf("dependency cycle: task %d depends on itself", taskID)}
+		}
+	}
+
+	// Проверяем, что все задачи, от которых зависит задача, существуют;
+	// задачи других пользователей считаются несуществующими
+	filter := &taskFilter{}
+	query := "SELECT id FROM tasks WHERE id IN (" + filter.argList(dependsOn) + ") AND user_id = " + filter.arg(userID) + " AND deleted_at IS NULL"
+	rows, err := h.db.Query(ctx, query, filter.args...)
+	if err != nil {
+		h.logQueryError(ctx, err, query, filter.args...)
+		return err
+	}
+	defer rows.Close()
+
+	found := make(map[int]bool, len(dependsOn))
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		found[id] = true
+	}
+	var missing []int
+	for _, id := range dependsOn {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return &relationError{http.StatusBadRequest, fmt.Sprintf("unknown dependency task IDs: %v", missing)}
+	}
+
+	if taskID == 0 {
+		return nil
+	}
+
+	// Обходим граф зависимостей от новых зависимостей: если из них достижима
+	// сама задача, новая связь замкнет цикл. UNION отбрасывает уже посещенные
+	// вершины, поэтому обход завершается и на существующих циклах.
+	filter = &taskFilter{}
+	query = `WITH RECURSIVE reachable(id) AS (
+			SELECT depends_on_id FROM task_dependencies WHERE task_id IN (` + filter.argList(dependsOn) + `)
+			UNION
+			SELECT d.depends_on_id FROM task_dependencies d JOIN reachable r ON d.task_id = r.id
+		)
+		SELECT EXISTS (SELECT 1 FROM reachable WHERE id = ` + filter.arg(taskID) + `)`
+	var cycle bool
+	if err := h.db.QueryRow(ctx, query, filter.args...).Scan(&cycle); err != nil {
+		h.logQueryError(ctx, err, query, filter.args...)
+		return err
+	}
+	if cycle {
+		return &relationError{http.StatusConflict, fmt.Sprintf("dependency cycle: task %d is already a prerequisite of %v", taskID, dependsOn)}
+	}
+	return nil
+}
+
+// writeRelationError отправляет клиенту ответ на ошибку checkDependencies или checkParent.
+func writeRelationError(ctx context.Context, w http.ResponseWriter, r *http.Request, err error) {
+	var relErr *relationError
+	if errors.As(err, &relErr) {
+		writeError(w, r, relErr.message, relErr.status)
+		return
+	}
+	writeServerError(ctx, w, r, err, "Server error")
+}
+
+// saveDependencies заменяет список задач, от которых зависит задача taskID.
+// Удаление старых связей и вставка новых должны выполняться в одной транзакции q.
+func (h *taskHandler) saveDependencies(ctx context.Context, q database.Querier, taskID int, dependsOn []int) error {
+	query := "DELETE FROM task_dependencies WHERE task_id=$1"
+	if _, err := q.Exec(ctx, query, taskID); err != nil {
+		h.logQueryError(ctx, err, query, taskID)
+		return err
+	}
+	if len(dependsOn) == 0 {
+		return nil
+	}
+
+	// Вставляем все связи одним запросом
+	filter := &taskFilter{}
+	values := make([]string, len(dependsOn))
+	for i, id := range dependsOn {
+		values[i] = "(" + filter.arg(taskID) + ", " + filter.arg(id) + ")"
+	}
+	query = "INSERT INTO task_dependencies (task_id, depends_on_id) VALUES " + strings.Join(values, ", ")
+	if _, err := q.Exec(ctx, query, filter.args...); err != nil {
+		h.logQueryError(ctx, err, query, filter.args...)
+		return err
+	}
+	return nil
+}
+
+// GetDependencies обрабатывает запрос на получение задач, от которых зависит задача с указанным ID.
+// Возвращает 404, если задача не найдена, иначе массив задач в формате JSON.
+func (h *taskHandler) GetDependencies(w http.ResponseWriter, r *http.Request) {
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Извлекаем ID задачи из параметров запроса
+	vars := mux.Vars(r)
+	taskID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		// Возвращаем ошибку при некорректном ID
+		writeError(w, r, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	// Проверяем, что задача существует
+	var exists int
+	userID := currentUserID(r)
+	query := "SELECT id FROM tasks WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL"
+	err = h.db.QueryRow(ctx, query, taskID, userID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		// Возвращаем ошибку, если задача не найдена
+		writeError(w, r, "Task not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, taskID, userID)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+
+	// Выполняем запрос на выборку задач, от которых зависит задача
+	query = "SELECT " + taskColumns + " FROM tasks WHERE id IN (SELECT depends_on_id FROM task_dependencies WHERE task_id=$1) AND deleted_at IS NULL ORDER BY id"
+	rows, err := h.db.Query(ctx, query, taskID)
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, taskID)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	// Итерируем по результатам выборки и заполняем срез задач
+	for rows.Next() {
+		var task models.Task
+		if err := scanTask(rows, &task); err != nil {
+			// Возвращаем ошибку сервера при сбое сканирования
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+		tasks = append(tasks, task)
+	}
+
+	// Возвращаем задачи в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, tasks)
+}
+
+// dependencyOrder упорядочивает задачи ids так, чтобы каждая шла после задач, от которых
+// она зависит (dependsOn сопоставляет задаче ее зависимости; зависимости вне ids
+// не учитываются). Из готовых к выполнению задач первой берется задача с меньшим ID,
+// поэтому порядок не зависит от порядка строк в базе данных. Если задачи образуют
+// цикл, порядок не строится, а возвращается один из циклов, начиная с задачи
+// с меньшим ID: каждая задача в нем зависит от следующей, последняя - от первой.
+func dependencyOrder(ids []int, dependsOn map[int][]int) (order, cycle []int) {
+	// Для каждой задачи считаем невыполненные зависимости и запоминаем зависящие от нее задачи
+	selected := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		selected[id] = true
+	}
+	pending := make(map[int]int, len(ids))
+	dependents := make(map[int][]int)
+	for _, id := range ids {
+		for _, dep := range dependsOn[id] {
+			if selected[dep] {
+				pending[id]++
+				dependents[dep] = append(dependents[dep], id)
+			}
+		}
+	}
+
+	ready := &intHeap{}
+	for _, id := range ids {
+		if pending[id] == 0 {
+			heap.Push(ready, id)
+		}
+	}
+	order = make([]int, 0, len(ids))
+	for ready.Len() > 0 {
+		id := heap.Pop(ready).(int)
+		order = append(order, id)
+		for _, dependent := range dependents[id] {
+			if pending[dependent]--; pending[dependent] == 0 {
+				heap.Push(ready, dependent)
+			}
+		}
+	}
+	if len(order) == len(ids) {
+		return order, nil
+	}
+
+	// У каждой оставшейся задачи есть оставшаяся зависимость, поэтому путь
+	// по ним от любой такой задачи рано или поздно замыкается в цикл
+	remaining := make([]int, 0, len(ids)-len(order))
+	for _, id := range ids {
+		if pending[id] > 0 {
+			remaining = append(remaining, id)
+		}
+	}
+	sort.Ints(remaining)
+	visited := make(map[int]int)
+	var path []int
+	for id := remaining[0]; ; {
+		if at, ok := visited[id]; ok {
+			// Начинаем цикл с задачи с меньшим ID, чтобы описание не зависело от точки входа
+			cycle = path[at:]
+			start := slices.Index(cycle, slices.Min(cycle))
+			return nil, slices.Concat(cycle[start:], cycle[:start])
+		}
+		visited[id] = len(path)
+		path = append(path, id)
+		next := 0
+		for _, dep := range dependsOn[id] {
+			if pending[dep] > 0 && (next == 0 || dep < next) {
+				next = dep
+			}
+		}
+		id = next
+	}
+}
+
+// intHeap - min-куча идентификаторов для container/heap.
+type intHeap []int
+
+func (h intHeap) Len() int           { return len(h) }
+func (h intHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h intHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *intHeap) Push(x interface{}) {
+	*h = append(*h, x.(int))
+}
+
+func (h *intHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// describeCycle описывает цикл зависимостей cycle для сообщения об ошибке.
+func describeCycle(cycle []int) string {
+	steps := make([]string, len(cycle))
+	for i, id := range cycle {
+		steps[i] = fmt.Sprintf("task %d depends on task %d", id, cycle[(i+1)%len(cycle)])
+	}
+	return "dependency cycle: " + strings.Join(steps, ", ")
+}
+
+// GetOrderedTasks обрабатывает запрос на получение задач в порядке зависимостей:
+// каждая задача идет после задач, от которых она зависит, поэтому их можно выполнять
+// по списку. Поддерживает те же фильтры, что и список задач; зависимости от задач,
+// не попавших в выборку, не учитываются. Если зависимости выбранных задач образуют
+// цикл (например, созданный до проверки циклов), возвращает 409 с описанием цикла.
+func (h *taskHandler) GetOrderedTasks(w http.ResponseWriter, r *http.Request) {
+	location, err := h.requestLocation(r)
+	if err != nil {
+		// Возвращаем ошибку при неизвестном часовом поясе
+		writeError(w, r, "Invalid tz parameter", http.StatusBadRequest)
+		return
+	}
+	filter, err := parseTaskFilter(r, location)
+	if err != nil {
+		// Возвращаем ошибку при некорректных параметрах фильтра
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Выбираем все подходящие задачи: упорядочить можно только выборку целиком
+	query := "SELECT " + taskColumns + " FROM tasks" + filter.clause() + " ORDER BY id"
+	rows, err := h.db.Query(ctx, query, filter.args...)
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, filter.args...)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	defer rows.Close()
+
+	tasks := make(map[int]models.Task)
+	ids := []int{}
+	for rows.Next() {
+		var task models.Task
+		if err := scanTask(rows, &task); err != nil {
+			// Возвращаем ошибку сервера при сбое сканирования
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+		tasks[task.ID] = task
+		ids = append(ids, task.ID)
+	}
+	rows.Close()
+
+	// Читаем зависимости задач пользователя одним запросом
+	userID := currentUserID(r)
+	query = "SELECT d.task_id, d.depends_on_id FROM task_dependencies d JOIN tasks t ON t.id = d.task_id WHERE t.user_id = $1"
+	edges, err := h.db.Query(ctx, query, userID)
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, userID)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	defer edges.Close()
+
+	dependsOn := make(map[int][]int)
+	for edges.Next() {
+		var taskID, dependsOnID int
+		if err := edges.Scan(&taskID, &dependsOnID); err != nil {
+			// Возвращаем ошибку сервера при сбое сканирования
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+		dependsOn[taskID] = append(dependsOn[taskID], dependsOnID)
+	}
+
+	order, cycle := dependencyOrder(ids, dependsOn)
+	if cycle != nil {
+		writeError(w, r, describeCycle(cycle), http.StatusConflict)
+		return
+	}
+	ordered := make([]models.Task, len(order))
+	for i, id := range order {
+		ordered[i] = tasks[id]
+	}
+
+	// Возвращаем упорядоченные задачи в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, ordered)
+}
@@ -0,0 +1,119 @@
+package hand
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/NickolaiP/taskApi/backend/internal/database/dbtest"
+)
+
+func TestDependencyOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		ids       []int
+		dependsOn map[int][]int
+		order     []int
+		cycle     []int
+	}{
+		{
+			name:  "no dependencies",
+			ids:   []int{3, 1, 2},
+			order: []int{1, 2, 3},
+		},
+		{
+			name:      "chain",
+			ids:       []int{1, 2, 3},
+			dependsOn: map[int][]int{1: {2}, 2: {3}},
+			order:     []int{3, 2, 1},
+		},
+		{
+			name:      "diamond",
+			ids:       []int{1, 2, 3, 4},
+			dependsOn: map[int][]int{1: {2, 3}, 2: {4}, 3: {4}},
+			order:     []int{4, 2, 3, 1},
+		},
+		{
+			name:      "dependency outside the selection",
+			ids:       []int{1, 2},
+			dependsOn: map[int][]int{1: {5}, 2: {1}},
+			order:     []int{1, 2},
+		},
+		{
+			name:      "cycle",
+			ids:       []int{1, 2, 3, 4},
+			dependsOn: map[int][]int{1: {4}, 2: {3}, 3: {4}, 4: {2}},
+			cycle:     []int{2, 3, 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, cycle := dependencyOrder(tt.ids, tt.dependsOn)
+			if !slices.Equal(order, tt.order) || !slices.Equal(cycle, tt.cycle) {
+				t.Errorf("dependencyOrder = %v, cycle %v; want %v, cycle %v", order, cycle, tt.order, tt.cycle)
+			}
+		})
+	}
+}
+
+func TestGetOrderedTasks(t *testing.T) {
+	tests := []struct {
+		name   string
+		edges  [][]driver.Value
+		status int
+		ids    []int
+	}{
+		{
+			name:   "ordered",
+			edges:  [][]driver.Value{{int64(7), int64(8)}},
+			status: http.StatusOK,
+			ids:    []int{8, 7},
+		},
+		{
+			name:   "cycle",
+			edges:  [][]driver.Value{{int64(7), int64(8)}, {int64(8), int64(7)}},
+			status: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New()
+			defer db.Close()
+			db.On("FROM task_dependencies").Rows([]string{"task_id", "depends_on_id"}, tt.edges...)
+			db.On("FROM tasks").Rows(strings.Split(taskColumns, ", "), taskRow(7), taskRow(8))
+
+			w := httptest.NewRecorder()
+			newTestTaskHandler(db).GetOrderedTasks(w, newTestRequest("GET", "/tasks/ordered", "", nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusConflict {
+				if want := "task 7 depends on task 8, task 8 depends on task 7"; !strings.Contains(w.Body.String(), want) {
+					t.Errorf("body = %q, want the cycle %q", w.Body, want)
+				}
+				return
+			}
+
+			var got []struct {
+				ID int `json:"id"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			var ids []int
+			for _, task := range got {
+				ids = append(ids, task.ID)
+			}
+			if !slices.Equal(ids, tt.ids) {
+				t.Errorf("order = %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package hand
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/database"
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// duplicateTitleSuffix добавляется к заголовку копии задачи.
+const duplicateTitleSuffix = " (copy)"
+
+// duplicateTitle возвращает заголовок копии задачи с заголовком title. Если с суффиксом
+// заголовок превысит MaxTitleLength символов, исходный заголовок укорачивается.
+func duplicateTitle(title string) string {
+	runes := []rune(title)
+	if limit := models.MaxTitleLength - len([]rune(duplicateTitleSuffix)); len(runes) > limit {
+		runes = runes[:limit]
+	}
+	return string(runes) + duplicateTitleSuffix
+}
+
+// DuplicateTask обрабатывает запрос на создание копии задачи с указанным ID.
+// Копия получает заголовок с суффиксом " (copy)", описание, срок выполнения и приоритет
+// исходной задачи; остальные поля заполняются как у новой задачи. Возвращает 404,
+// если исходной задачи нет, иначе созданную задачу в формате JSON с адресом в заголовке Location.
+func (h *taskHandler) DuplicateTask(w http.ResponseWriter, r *http.Request) {
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Извлекаем ID задачи из параметров запроса
+	vars := mux.Vars(r)
+	taskID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		// Возвращаем ошибку при некорректном ID
+		writeError(w, r, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	// Выбираем исходную задачу
+	var source models.Task
+	userID := currentUserID(r)
+	query := "SELECT " + taskColumns + " FROM tasks WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL"
+	err = scanTask(h.db.QueryRow(ctx, query, taskID, userID), &source)
+	if err == sql.ErrNoRows {
+		// Возвращаем ошибку, если задача не найдена
+		writeError(w, r, "Task not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, taskID, userID)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+
+	// Заполняем копию задачи
+	task := models.Task{
+		Title:       duplicateTitle(source.Title),
+		Description: source.Description,
+		Status:      models.StatusPending,
+		Priority:    source.Priority,
+		DueDate:     source.DueDate,
+		UserID:      userID,
+	}
+	setCreationTime(&task, time.Now().Format(time.RFC3339))
+
+	// Вставляем копию и запись о ее создании в журнал изменений в одной транзакции
+	err = h.withTx(ctx, func(tx database.Tx) error {
+		args := insertTaskArgs(&task)
+		if err := tx.QueryRow(ctx, insertTaskQuery, args...).Scan(&task.ID, &task.Position, &task.Version); err != nil {
+			h.logQueryError(ctx, err, insertTaskQuery, args...)
+			return err
+		}
+		return h.audit(ctx, tx, userID, task.ID, models.AuditCreated, auditDiff(nil, task))
+	})
+	if err != nil {
+		// Возвращаем ошибку сервера, если вставка не удалась
+		writeServerError(ctx, w, r, err, "Error creating task")
+		return
+	}
+
+	// Указываем адрес и версию созданной задачи
+	w.Header().Set("Location", taskLocation(task.ID))
+	w.Header().Set("ETag", taskETag(task.Version))
+
+	// Устанавливаем статус ответа как Created и возвращаем созданную задачу
+	writeResponse(h.log(r.Context()), w, r, http.StatusCreated, task)
+}
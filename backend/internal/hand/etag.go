@@ -0,0 +1,89 @@
+package hand
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// anyVersion - ожидаемая версия для условия "If-Match: *", которому удовлетворяет
+// любая версия существующей задачи. Версии задач начинаются с 1.
+const anyVersion = 0
+
+// errIfMatchRequired возвращается, если запрос на изменение задачи не содержит If-Match.
+var errIfMatchRequired = errors.New("If-Match header is required")
+
+// errInvalidIfMatch возвращается, если If-Match не является ETag задачи или "*".
+var errInvalidIfMatch = errors.New(`If-Match must be a task ETag such as "3" or *`)
+
+// taskETag возвращает значение заголовка ETag для версии задачи.
+func taskETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseIfMatch извлекает из заголовка If-Match версию задачи, которую клиент
+// видел перед изменением. Для "*" возвращает anyVersion.
+func parseIfMatch(r *http.Request) (int, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" {
+		return 0, errIfMatchRequired
+	}
+	if value == "*" {
+		return anyVersion, nil
+	}
+	// Слабые ETag (W/"...") для If-Match не подходят: требуется точное совпадение версии
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return 0, errInvalidIfMatch
+	}
+	version, err := strconv.Atoi(value[1 : len(value)-1])
+	if err != nil || version <= 0 {
+		return 0, errInvalidIfMatch
+	}
+	return version, nil
+}
+
+// writeIfMatchError записывает в ответ ошибку разбора заголовка If-Match:
+// 428 для отсутствующего заголовка и 400 для некорректного.
+func writeIfMatchError(w http.ResponseWriter, r *http.Request, err error) {
+	if err == errIfMatchRequired {
+		writeError(w, r, err.Error(), http.StatusPreconditionRequired)
+		return
+	}
+	writeError(w, r, err.Error(), http.StatusBadRequest)
+}
+
+// versionMatches сообщает, совпадает ли текущая версия задачи с ожидаемой.
+func versionMatches(expected, current int) bool {
+	return expected == anyVersion || expected == current
+}
+
+// writeVersionConflict записывает в ответ 409 с текущей версией задачи в заголовке ETag,
+// чтобы клиент мог перечитать задачу и повторить изменение.
+func writeVersionConflict(w http.ResponseWriter, r *http.Request, current int) {
+	w.Header().Set("ETag", taskETag(current))
+	writeError(w, r, "Task has been modified: version does not match If-Match", http.StatusConflict)
+}
+
+// writeUpdateMiss отвечает на условное изменение задачи taskID, не затронувшее
+// ни одной строки: 404, если задачи нет или она удалена, иначе 409 - задачу
+// успели изменить после того, как клиент (или обработчик) прочитал ее версию.
+func (h *taskHandler) writeUpdateMiss(ctx context.Context, w http.ResponseWriter, r *http.Request, userID, taskID int) {
+	var current int
+	query := "SELECT version FROM tasks WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL"
+	err := h.db.QueryRow(ctx, query, taskID, userID).Scan(&current)
+	if err == sql.ErrNoRows {
+		// Возвращаем ошибку, если задача не найдена
+		writeError(w, r, "Task not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, taskID, userID)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	writeVersionConflict(w, r, current)
+}
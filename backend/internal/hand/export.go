@@ -0,0 +1,252 @@
+package hand
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// exportTimeout - минимальный таймаут выгрузки: в отличие от страниц списка задач,
+// выгрузка читает все задачи пользователя и может занимать больше времени,
+// чем общий таймаут DB_QUERY_TIMEOUT.
+const exportTimeout = time.Minute
+
+// exportFlushRows - через сколько задач буферизованный вывод выгрузки
+// отправляется клиенту.
+const exportFlushRows = 100
+
+// taskExportColumns дополняет taskColumns метками задачи, чтобы выгрузка
+// получала их тем же запросом, а не отдельным запросом на каждую задачу.
+const taskExportColumns = taskColumns + ", ARRAY(SELECT t.name FROM task_tags tt JOIN tags t ON t.id = tt.tag_id WHERE tt.task_id = tasks.id ORDER BY t.name)"
+
+// scanFunc позволяет использовать функцию как rowScanner.
+type scanFunc func(dest ...interface{}) error
+
+func (f scanFunc) Scan(dest ...interface{}) error {
+	return f(dest...)
+}
+
+// scanExportTask считывает строку, выбранную по taskExportColumns, в структуру задачи.
+func scanExportTask(row rowScanner, task *models.Task) error {
+	var tags []string
+	err := scanTask(scanFunc(func(dest ...interface{}) error {
+		return row.Scan(append(dest, pq.Array(&tags))...)
+	}), task)
+	task.Tags = tags
+	return err
+}
+
+// taskExporter записывает задачи в ответ в одном из форматов выгрузки.
+type taskExporter interface {
+	// contentType возвращает тип содержимого ответа.
+	contentType() string
+	// extension возвращает расширение имени скачиваемого файла.
+	extension() string
+	// write записывает очередную задачу.
+	write(task models.Task) error
+	// flush отправляет клиенту накопленный вывод.
+	flush() error
+	// close завершает выгрузку и отправляет клиенту остаток вывода.
+	close() error
+}
+
+// csvColumns - заголовок выгрузки в формате CSV.
+var csvColumns = []string{"id", "title", "description", "status", "priority", "due_date", "tags", "blocked", "blocked_reason",
+	"position", "created_at", "updated_at", "completed_at", "recurrence", "recurrence_interval", "remind_at", "parent_id"}
+
+// csvExporter выгружает задачи в формате CSV: строка заголовка и по строке на задачу.
+type csvExporter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (e *csvExporter) contentType() string { return "text/csv; charset=utf-8" }
+
+func (e *csvExporter) extension() string { return "csv" }
+
+func (e *csvExporter) write(task models.Task) error {
+	if !e.header {
+		e.header = true
+		if err := e.w.Write(csvColumns); err != nil {
+			return err
+		}
+	}
+	// Нулевые значения необязательных полей выгружаются пустыми ячейками
+	var interval, parentID string
+	if task.RecurrenceInterval > 0 {
+		interval = strconv.Itoa(task.RecurrenceInterval)
+	}
+	if task.ParentID > 0 {
+		parentID = strconv.Itoa(task.ParentID)
+	}
+	return e.w.Write([]string{
+		strconv.Itoa(task.ID), task.Title, task.Description, task.Status, task.Priority, task.DueDate,
+		strings.Join(task.Tags, ","), strconv.FormatBool(task.Blocked), task.BlockedReason,
+		strconv.FormatFloat(task.Position, 'f', -1, 64), task.CreatedAt, task.UpdatedAt, task.CompletedAt,
+		task.Recurrence, interval, task.RemindAt, parentID,
+	})
+}
+
+func (e *csvExporter) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExporter) close() error {
+	// Заголовок выводится и для пустой выгрузки
+	if !e.header {
+		e.header = true
+		if err := e.w.Write(csvColumns); err != nil {
+			return err
+		}
+	}
+	return e.flush()
+}
+
+// jsonExporter выгружает задачи в формате JSON-массива с отступами.
+// Задачи кодируются по одной, поэтому массив не собирается в памяти целиком.
+type jsonExporter struct {
+	w     *bufio.Writer
+	count int
+}
+
+func (e *jsonExporter) contentType() string { return "application/json" }
+
+func (e *jsonExporter) extension() string { return "json" }
+
+func (e *jsonExporter) write(task models.Task) error {
+	data, err := json.MarshalIndent(task, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	separator := ",\n  "
+	if e.count == 0 {
+		separator = "[\n  "
+	}
+	e.count++
+	if _, err := e.w.WriteString(separator); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonExporter) flush() error {
+	return e.w.Flush()
+}
+
+func (e *jsonExporter) close() error {
+	end := "\n]\n"
+	if e.count == 0 {
+		end = "[]\n"
+	}
+	if _, err := e.w.WriteString(end); err != nil {
+		return err
+	}
+	return e.flush()
+}
+
+// newTaskExporter возвращает выгрузку в формате format (csv или json) в w.
+// Пустой формат означает CSV.
+func newTaskExporter(format string, w http.ResponseWriter) (taskExporter, bool) {
+	switch format {
+	case "", "csv":
+		return &csvExporter{w: csv.NewWriter(w)}, true
+	case "json":
+		return &jsonExporter{w: bufio.NewWriter(w)}, true
+	}
+	return nil, false
+}
+
+// ExportTasks обрабатывает запрос на выгрузку задач в файл. Формат задается
+// параметром format: csv (по умолчанию) или json. Поддерживает те же фильтры
+// и сортировку, что и GetTasks, но выгружает все подходящие задачи без
+// постраничного вывода. Задачи отправляются клиенту по мере чтения из базы данных,
+// а заголовок Content-Disposition предлагает браузеру сохранить ответ как файл.
+func (h *taskHandler) ExportTasks(w http.ResponseWriter, r *http.Request) {
+	// Определяем формат выгрузки
+	exporter, ok := newTaskExporter(r.URL.Query().Get("format"), w)
+	if !ok {
+		writeError(w, r, "format must be csv or json", http.StatusBadRequest)
+		return
+	}
+
+	// Определяем часовой пояс для дат без времени в параметрах фильтрации
+	location, err := h.requestLocation(r)
+	if err != nil {
+		// Возвращаем ошибку при неизвестном часовом поясе
+		writeError(w, r, "Invalid tz parameter", http.StatusBadRequest)
+		return
+	}
+
+	// Разбираем параметры фильтрации и сортировки так же, как для списка задач
+	filter, err := parseTaskFilter(r, location)
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	order, err := parseTaskSort(r)
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.relevance != "" && r.URL.Query().Get("sort") == "" {
+		order = filter.relevance + " DESC, id"
+	}
+
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), max(h.queryTimeout, exportTimeout))
+	defer cancel()
+
+	// Выполняем запрос на выборку всех задач, удовлетворяющих фильтрам
+	query := "SELECT " + taskExportColumns + " FROM tasks" + filter.clause() + " ORDER BY " + order
+	rows, err := h.db.Query(ctx, query, filter.args...)
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, filter.args...)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	defer rows.Close()
+
+	// После начала выгрузки статус ответа изменить уже нельзя, поэтому
+	// ошибки дальше только записываются в лог, а выгрузка прерывается
+	w.Header().Set("Content-Type", exporter.contentType())
+	w.Header().Set("Content-Disposition", `attachment; filename="tasks.`+exporter.extension()+`"`)
+
+	count := 0
+	for rows.Next() {
+		var task models.Task
+		if err := scanExportTask(rows, &task); err != nil {
+			h.log(ctx).Error("Failed to scan exported task", "error", err)
+			return
+		}
+		if err := exporter.write(task); err != nil {
+			h.log(ctx).Error("Failed to write exported task", "error", err)
+			return
+		}
+		count++
+		if count%exportFlushRows == 0 {
+			if err := exporter.flush(); err != nil {
+				h.log(ctx).Error("Failed to write exported tasks", "error", err)
+				return
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		h.logQueryError(ctx, err, query, filter.args...)
+		return
+	}
+	if err := exporter.close(); err != nil {
+		h.log(ctx).Error("Failed to write exported tasks", "error", err)
+	}
+}
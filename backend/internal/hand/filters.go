@@ -0,0 +1,306 @@
+package hand
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+)
+
+const (
+	// defaultPageLimit - размер страницы списка задач, если параметр limit не указан.
+	defaultPageLimit = 50
+	// maxPageLimit - максимальный размер страницы; большие значения limit урезаются до него.
+	maxPageLimit = 100
+)
+
+// taskSearchDocument - текст задачи для полнотекстового поиска. Выражение должно
+// совпадать с выражением индекса tasks_fulltext_idx, иначе индекс не используется.
+const taskSearchDocument = "to_tsvector('simple', title || ' ' || description)"
+
+// metadataParamPrefix - префикс параметров запроса, фильтрующих задачи
+// по ключам метаданных, например metadata.project=alpha.
+const metadataParamPrefix = "metadata."
+
+// taskFilter накапливает условия WHERE и их аргументы для выборки задач.
+// Аргументы нумеруются в порядке добавления, поэтому условия можно
+// свободно комбинировать без ручного подсчета плейсхолдеров.
+type taskFilter struct {
+	conditions []string
+	args       []interface{}
+
+	// relevance - выражение релевантности полнотекстового поиска для сортировки
+	// или пустая строка, если поиск не запрошен.
+	relevance string
+
+	// criteria - количество условий, заданных параметрами запроса, без условий,
+	// которые parseTaskFilter добавляет всегда (владелец и удаленные задачи).
+	criteria int
+}
+
+// arg добавляет аргумент запроса и возвращает его плейсхолдер вида $N.
+func (f *taskFilter) arg(value interface{}) string {
+	f.args = append(f.args, value)
+	return "$" + strconv.Itoa(len(f.args))
+}
+
+// argList добавляет список идентификаторов как отдельные аргументы и возвращает
+// их плейсхолдеры через запятую для подстановки в IN (...).
+func (f *taskFilter) argList(ids []int) string {
+	placeholders := make([]string, len(ids))
+	for i, id := range ids {
+		placeholders[i] = f.arg(id)
+	}
+	return strings.Join(placeholders, ", ")
+}
+
+// argStrings добавляет список строк как отдельные аргументы и возвращает
+// их плейсхолдеры через запятую для подстановки в IN (...).
+func (f *taskFilter) argStrings(values []string) string {
+	placeholders := make([]string, len(values))
+	for i, value := range values {
+		placeholders[i] = f.arg(value)
+	}
+	return strings.Join(placeholders, ", ")
+}
+
+// where добавляет условие, которое будет объединено с остальными через AND.
+func (f *taskFilter) where(condition string) {
+	f.conditions = append(f.conditions, condition)
+}
+
+// clause возвращает часть запроса WHERE или пустую строку, если условий нет.
+func (f *taskFilter) clause() string {
+	if len(f.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(f.conditions, " AND ")
+}
+
+// likeEscaper экранирует спецсимволы шаблона LIKE, чтобы пользовательский ввод
+// сравнивался буквально. Используется вместе с ESCAPE '\' в запросе.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike экранирует строку для безопасной подстановки в шаблон LIKE.
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+// parseTaskFilter разбирает параметры запроса списка задач в условия выборки.
+// Выборка всегда ограничена задачами аутентифицированного пользователя.
+// Даты без времени в параметрах интервала срока выполнения и границы дня
+// в быстрых фильтрах отсчитываются в часовом поясе location.
+func parseTaskFilter(r *http.Request, location *time.Location) (*taskFilter, error) {
+	filter := &taskFilter{}
+	query := r.URL.Query()
+	filter.where("user_id = " + filter.arg(currentUserID(r)))
+
+	// Удаленные задачи выводятся только по явному запросу include_deleted=true
+	includeDeleted := false
+	if value := query.Get("include_deleted"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New("include_deleted must be true or false")
+		}
+		includeDeleted = parsed
+	}
+	if !includeDeleted {
+		filter.where("deleted_at IS NULL")
+	}
+	base := len(filter.conditions)
+
+	// Фильтр по статусу; несколько значений (?status=a&status=b) объединяются через IN
+	if statuses := query["status"]; len(statuses) > 0 {
+		placeholders := make([]string, len(statuses))
+		for i, status := range statuses {
+			if !models.IsValidStatus(status) {
+				return nil, fmt.Errorf("unknown status %q: must be one of %s", status, strings.Join(models.Statuses, ", "))
+			}
+			placeholders[i] = filter.arg(status)
+		}
+		if len(placeholders) == 1 {
+			filter.where("status = " + placeholders[0])
+		} else {
+			filter.where("status IN (" + strings.Join(placeholders, ", ") + ")")
+		}
+	}
+
+	// Фильтр по метке; несколько значений (?tag=a&tag=b) выбирают задачи с любой из меток
+	if tags := query["tag"]; len(tags) > 0 {
+		placeholders := make([]string, len(tags))
+		for i, tag := range tags {
+			placeholders[i] = filter.arg(models.NormalizeTag(tag))
+		}
+		filter.where("id IN (SELECT tt.task_id FROM task_tags tt JOIN tags t ON t.id = tt.tag_id WHERE t.name IN (" + strings.Join(placeholders, ", ") + "))")
+	}
+
+	// Фильтр по признаку блокировки
+	if value := query.Get("blocked"); value != "" {
+		blocked, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New("blocked must be true or false")
+		}
+		filter.where("blocked = " + filter.arg(blocked))
+	}
+
+	// Интервал срока выполнения; задачи без срока при любой заданной границе исключаются.
+	// Дата без времени в due_before включает весь этот день
+	dueAfter, err := parseTimeParam(r, "due_after", location)
+	if err != nil {
+		return nil, err
+	}
+	dueBefore, err := parseTimeParam(r, "due_before", location)
+	if err != nil {
+		return nil, err
+	}
+	if !dueAfter.IsZero() && !dueBefore.IsZero() && dueAfter.After(dueBefore) {
+		return nil, errors.New("due_after must not be later than due_before")
+	}
+	if !dueAfter.IsZero() {
+		filter.where("due_date >= " + filter.arg(dueAfter.UTC()))
+	}
+	if !dueBefore.IsZero() {
+		if isDateOnly(query.Get("due_before")) {
+			filter.where("due_date < " + filter.arg(dueBefore.AddDate(0, 0, 1).UTC()))
+		} else {
+			filter.where("due_date <= " + filter.arg(dueBefore.UTC()))
+		}
+	}
+
+	// Быстрые фильтры по сроку выполнения; границы дня считаются в часовом поясе location.
+	// Просроченными считаются невыполненные задачи со сроком раньше текущего момента,
+	// как и в повестке
+	if value := query.Get("filter"); value != "" {
+		now := time.Now().In(location)
+		switch value {
+		case "overdue":
+			filter.where("due_date < " + filter.arg(now.UTC()))
+			filter.where("status <> " + filter.arg(models.StatusDone))
+		case "due_today":
+			today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+			filter.where("due_date >= " + filter.arg(today.UTC()))
+			filter.where("due_date < " + filter.arg(today.AddDate(0, 0, 1).UTC()))
+		default:
+			return nil, errors.New("filter must be one of: overdue, due_today")
+		}
+	}
+
+	// Поиск по ключевым словам в заголовке и описании: по умолчанию - подстрока
+	// без учета регистра, с search_mode=fulltext - полнотекстовый поиск
+	if q := query.Get("q"); q != "" {
+		switch query.Get("search_mode") {
+		case "", "substring":
+			pattern := "'%' || " + filter.arg(escapeLike(q)) + " || '%'"
+			filter.where("(title ILIKE " + pattern + ` ESCAPE '\' OR description ILIKE ` + pattern + ` ESCAPE '\')`)
+		case "fulltext":
+			tsQuery := "plainto_tsquery('simple', " + filter.arg(q) + ")"
+			filter.where(taskSearchDocument + " @@ " + tsQuery)
+			filter.relevance = "ts_rank(" + taskSearchDocument + ", " + tsQuery + ")"
+		default:
+			return nil, errors.New("search_mode must be substring or fulltext")
+		}
+	}
+
+	// Собираем фильтры по метаданным в отсортированном порядке,
+	// чтобы текст запроса не зависел от порядка обхода map
+	var keys []string
+	for param := range query {
+		if strings.HasPrefix(param, metadataParamPrefix) {
+			keys = append(keys, param)
+		}
+	}
+	sort.Strings(keys)
+	for _, param := range keys {
+		key := strings.TrimPrefix(param, metadataParamPrefix)
+		filter.where("metadata->>" + filter.arg(key) + " = " + filter.arg(query.Get(param)))
+	}
+
+	filter.criteria = len(filter.conditions) - base
+	return filter, nil
+}
+
+// priorityRank - ранг приоритета для сортировки: сначала высокий приоритет, затем средний и низкий.
+const priorityRank = "CASE priority WHEN 'high' THEN 0 WHEN 'medium' THEN 1 ELSE 2 END"
+
+// taskSortOrders сопоставляет допустимые значения параметра sort с выражениями ORDER BY.
+// ID в конце каждого выражения делает порядок однозначным и страницы стабильными.
+var taskSortOrders = map[string]string{
+	"id":       "id",
+	"position": "position, id",
+	"priority": priorityRank + ", id",
+}
+
+// parseTaskSort разбирает параметр sort запроса списка задач в выражение ORDER BY.
+// Без параметра задачи сортируются по ID.
+func parseTaskSort(r *http.Request) (string, error) {
+	value := r.URL.Query().Get("sort")
+	if value == "" {
+		return taskSortOrders["id"], nil
+	}
+	order, ok := taskSortOrders[value]
+	if !ok {
+		return "", errors.New("sort must be one of: id, position, priority")
+	}
+	return order, nil
+}
+
+// pagination описывает страницу списка задач, запрошенную параметрами limit и offset.
+type pagination struct {
+	limit  int
+	offset int
+}
+
+// parsePagination разбирает параметры limit и offset запроса.
+// Отсутствующие параметры заменяются значениями по умолчанию, limit больше
+// maxPageLimit урезается, а нечисловые или отрицательные значения считаются ошибкой.
+func parsePagination(r *http.Request) (pagination, error) {
+	page := pagination{limit: defaultPageLimit}
+	query := r.URL.Query()
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return page, errors.New("limit must be a positive integer")
+		}
+		page.limit = min(limit, maxPageLimit)
+	}
+
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return page, errors.New("offset must be a non-negative integer")
+		}
+		page.offset = offset
+	}
+
+	return page, nil
+}
+
+// parseTimeParam разбирает момент времени из параметра запроса name.
+// Допускаются значения в формате RFC3339 и даты вида 2006-01-02, которые
+// означают полночь в часовом поясе location. Пустой параметр возвращает нулевое время.
+func parseTimeParam(r *http.Request, name string, location *time.Location) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(location), nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, value, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC3339 timestamp or a date (YYYY-MM-DD)", name)
+	}
+	return t, nil
+}
+
+// isDateOnly сообщает, является ли значение датой без времени вида 2006-01-02.
+func isDateOnly(value string) bool {
+	_, err := time.Parse(time.DateOnly, value)
+	return err == nil
+}
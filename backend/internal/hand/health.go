@@ -0,0 +1,53 @@
+package hand
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/database"
+	"github.com/NickolaiP/taskApi/backend/internal/logger"
+)
+
+// healthHandler представляет собой структуру обработчика проверок состояния сервиса.
+// Включает в себя подключение к базе данных и логгер.
+type healthHandler struct {
+	db     database.Database
+	logger *logger.Logger
+}
+
+// NewHealthHandler создает новый экземпляр healthHandler с заданными базой данных и логгером.
+func NewHealthHandler(db database.Database, logger *logger.Logger) *healthHandler {
+	return &healthHandler{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// healthStatus - ответ проверок состояния сервиса.
+type healthStatus struct {
+	Status string `json:"status" xml:"status"`
+}
+
+// Health обрабатывает проверку живости (liveness): если процесс отвечает на запросы,
+// возвращает 200 и {"status":"ok"}. База данных при этом не проверяется.
+func (h *healthHandler) Health(w http.ResponseWriter, r *http.Request) {
+	writeResponse(logger.FromContext(r.Context(), h.logger), w, r, http.StatusOK, healthStatus{Status: "ok"})
+}
+
+// Ready обрабатывает проверку готовности (readiness): возвращает 200 и {"status":"ready"},
+// если база данных доступна, иначе 503 и {"status":"unavailable"}.
+func (h *healthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	// Создаем контекст с коротким таймаутом, чтобы проверка не зависала
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	if err := h.db.Ping(ctx); err != nil {
+		// Логируем недоступность базы данных и сообщаем, что сервис не готов
+		logger.FromContext(ctx, h.logger).Warn("Readiness check failed", "error", err)
+		writeResponse(logger.FromContext(r.Context(), h.logger), w, r, http.StatusServiceUnavailable, healthStatus{Status: "unavailable"})
+		return
+	}
+
+	writeResponse(logger.FromContext(r.Context(), h.logger), w, r, http.StatusOK, healthStatus{Status: "ready"})
+}
@@ -0,0 +1,386 @@
+package hand
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/database"
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// patchableFields перечисляет поля задачи, которые можно передать в частичном обновлении.
+var patchableFields = []string{"title", "description", "status", "priority", "due_date", "remind_at", "metadata",
+	"recurrence", "recurrence_interval", "parent_id", "tags", "depends_on"}
+
+// checkPatchFields возвращает ошибку с именем первого (по алфавиту) поля запроса,
+// которого нет в patchableFields, в том же виде, что и decodeStrict.
+func checkPatchFields(fields map[string]json.RawMessage) error {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !slices.Contains(patchableFields, name) {
+			return fmt.Errorf("%s%q", unknownFieldPrefix, name)
+		}
+	}
+	return nil
+}
+
+// patchStringField декодирует обязательное строковое поле частичного обновления.
+// Значение null для таких полей недопустимо.
+func patchStringField(fields map[string]json.RawMessage, name string) (string, bool, error) {
+	raw, ok := fields[name]
+	if !ok {
+		return "", false, nil
+	}
+	var value *string
+	if err := json.Unmarshal(raw, &value); err != nil || value == nil {
+		return "", true, fmt.Errorf("%s must be a string", name)
+	}
+	return *value, true, nil
+}
+
+// validatePatchFields проверяет переданные в частичном обновлении заголовок, описание
+// и срок выполнения теми же правилами, что и models.Task.Validate. Ошибки по полям,
+// отсутствующим в запросе, не учитываются: их значения в patched не заполнены.
+func validatePatchFields(fields map[string]json.RawMessage, patched *models.Task) error {
+	err := patched.Validate()
+	all, ok := err.(models.ValidationErrors)
+	if !ok {
+		return err
+	}
+	errs := models.ValidationErrors{}
+	for _, name := range []string{"title", "description", "due_date"} {
+		if _, present := fields[name]; present && all[name] != "" {
+			errs[name] = all[name]
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// PatchTask обрабатывает запрос на частичное обновление задачи по её ID.
+// Обновляются только поля, присутствующие в теле запроса (title, description, status,
+// priority, due_date, remind_at, metadata, recurrence, recurrence_interval, parent_id, tags, depends_on);
+// остальные поля сохраняют текущие значения, а неизвестные поля отклоняются с ответом 400. Значение null в due_date, remind_at
+// и metadata очищает поле, в recurrence - отключает повторение, в parent_id -
+// делает задачу задачей верхнего уровня, в tags - удаляет метки. Переданные title, description
+// и due_date проверяются так же, как при создании задачи. Как и для PUT, заголовок
+// If-Match с ETag задачи обязателен, а при несовпадении версии возвращается 409.
+// Возвращает обновленную задачу в формате JSON.
+func (h *taskHandler) PatchTask(w http.ResponseWriter, r *http.Request) {
+	// Определяем версию задачи, которую изменяет клиент
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		writeIfMatchError(w, r, err)
+		return
+	}
+
+	// Декодируем тело запроса в набор полей, чтобы отличать отсутствующие поля от пустых
+	var fields map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+		// Возвращаем ошибку при некорректном или пустом запросе
+		writePayloadError(w, r, err)
+		return
+	}
+	// Поле с опечаткой в имени отклоняется, а не пропускается молча
+	if err := checkPatchFields(fields); err != nil {
+		writePayloadError(w, r, err)
+		return
+	}
+
+	// Извлекаем ID задачи из параметров запроса
+	vars := mux.Vars(r)
+	taskID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		// Возвращаем ошибку при некорректном ID
+		writeError(w, r, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	// Собираем присваивания только для переданных полей; нумерация
+	// аргументов ведется так же, как при построении фильтров
+	set := &taskFilter{}
+	var assignments []string
+	now := time.Now().Format(time.RFC3339)
+
+	// Переданные заголовок, описание и срок выполнения проверяются вместе, как при создании задачи
+	var patched models.Task
+	title, ok, err := patchStringField(fields, "title")
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if ok {
+		patched.Title = title
+		assignments = append(assignments, "title="+set.arg(title))
+	}
+	description, ok, err := patchStringField(fields, "description")
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if ok {
+		patched.Description = description
+		assignments = append(assignments, "description="+set.arg(description))
+	}
+
+	status, ok, err := patchStringField(fields, "status")
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if ok {
+		if !models.IsValidStatus(status) {
+			writeError(w, r, "status must be one of: pending, in_progress, done", http.StatusBadRequest)
+			return
+		}
+		// Время выполнения сохраняется, пока задача остается выполненной
+		statusArg := set.arg(status)
+		assignments = append(assignments, "status="+statusArg,
+			"completed_at=CASE WHEN "+statusArg+" = 'done' THEN COALESCE(completed_at, "+set.arg(now)+"::timestamp) END")
+	}
+
+	priority, ok, err := patchStringField(fields, "priority")
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if ok {
+		if !models.IsValidPriority(priority) {
+			writeError(w, r, "priority must be one of: low, medium, high", http.StatusBadRequest)
+			return
+		}
+		assignments = append(assignments, "priority="+set.arg(priority))
+	}
+
+	if raw, ok := fields["due_date"]; ok {
+		var dueDate *string
+		if err := json.Unmarshal(raw, &dueDate); err != nil {
+			writeError(w, r, "due_date must be a string or null", http.StatusBadRequest)
+			return
+		}
+		if dueDate != nil {
+			patched.DueDate = *dueDate
+		}
+		assignments = append(assignments, "due_date="+set.arg(dueDateArg(patched.DueDate)))
+	}
+	if err := validatePatchFields(fields, &patched); err != nil {
+		h.writeValidationError(r.Context(), w, r, err)
+		return
+	}
+
+	if raw, ok := fields["remind_at"]; ok {
+		var remindAt *string
+		if err := json.Unmarshal(raw, &remindAt); err != nil {
+			writeError(w, r, "remind_at must be a string or null", http.StatusBadRequest)
+			return
+		}
+		task := models.Task{}
+		if remindAt != nil {
+			task.RemindAt = *remindAt
+		}
+		if err := task.ValidateRemindAt(); err != nil {
+			writeError(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Новое время напоминания снова делает напоминание неотправленным
+		remindArg := set.arg(remindAtArg(task.RemindAt))
+		assignments = append(assignments, "remind_at="+remindArg,
+			"reminded=(reminded AND remind_at IS NOT DISTINCT FROM "+remindArg+"::timestamp)")
+	}
+
+	if raw, ok := fields["metadata"]; ok {
+		// Проверяем метаданные теми же правилами, что и при полном обновлении
+		task := models.Task{Metadata: raw}
+		if err := task.ValidateMetadata(); err != nil {
+			writeError(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		assignments = append(assignments, "metadata="+set.arg(metadataArg(task.Metadata)))
+	}
+
+	// Повторение: null или пустая строка отключают его, интервал можно менять отдельно
+	var interval *int
+	if raw, ok := fields["recurrence_interval"]; ok {
+		if err := json.Unmarshal(raw, &interval); err != nil || interval == nil || *interval <= 0 {
+			writeError(w, r, "recurrence_interval must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		assignments = append(assignments, "recurrence_interval="+set.arg(*interval))
+	}
+	if raw, ok := fields["recurrence"]; ok {
+		var recurrence *string
+		if err := json.Unmarshal(raw, &recurrence); err != nil {
+			writeError(w, r, "recurrence must be a string or null", http.StatusBadRequest)
+			return
+		}
+		switch {
+		case recurrence == nil || *recurrence == "":
+			if interval != nil {
+				writeError(w, r, "recurrence_interval requires recurrence", http.StatusBadRequest)
+				return
+			}
+			assignments = append(assignments, "recurrence=NULL", "recurrence_interval=1")
+		case models.IsValidRecurrence(*recurrence):
+			assignments = append(assignments, "recurrence="+set.arg(*recurrence))
+		default:
+			writeError(w, r, "recurrence must be one of: daily, weekly, monthly", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Родительская задача: null делает задачу задачей верхнего уровня
+	var parentID *int
+	_, patchParent := fields["parent_id"]
+	if patchParent {
+		if err := json.Unmarshal(fields["parent_id"], &parentID); err != nil || (parentID != nil && *parentID <= 0) {
+			writeError(w, r, "parent_id must be a positive task ID or null", http.StatusBadRequest)
+			return
+		}
+		var value int
+		if parentID != nil {
+			value = *parentID
+		}
+		assignments = append(assignments, "parent_id="+set.arg(parentIDArg(value)))
+	}
+
+	var tags []string
+	_, patchTags := fields["tags"]
+	if patchTags {
+		if err := json.Unmarshal(fields["tags"], &tags); err != nil {
+			writeError(w, r, "tags must be an array of strings", http.StatusBadRequest)
+			return
+		}
+		// null равнозначен пустому списку: метки удаляются
+		task := models.Task{Tags: tags}
+		if task.Tags == nil {
+			task.Tags = []string{}
+		}
+		if err := task.ValidateTags(); err != nil {
+			writeError(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tags = task.Tags
+	}
+
+	var dependsOn []int
+	_, patchDependencies := fields["depends_on"]
+	if patchDependencies {
+		if err := json.Unmarshal(fields["depends_on"], &dependsOn); err != nil {
+			writeError(w, r, "depends_on must be an array of task IDs", http.StatusBadRequest)
+			return
+		}
+		// null равнозначен пустому списку: зависимости удаляются
+		if dependsOn == nil {
+			dependsOn = []int{}
+		}
+		dependsOn = uniqueIDs(dependsOn)
+	}
+
+	// Запрос без обновляемых полей считается ошибкой клиента
+	if len(assignments) == 0 && !patchDependencies && !patchTags {
+		writeError(w, r, "Request must contain at least one updatable field", http.StatusBadRequest)
+		return
+	}
+
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Проверяем новые зависимости задачи
+	if patchDependencies {
+		if err := h.checkDependencies(ctx, currentUserID(r), taskID, dependsOn); err != nil {
+			writeRelationError(ctx, w, r, err)
+			return
+		}
+	}
+	// Проверяем новую родительскую задачу
+	if parentID != nil {
+		if err := h.checkParent(ctx, currentUserID(r), taskID, *parentID); err != nil {
+			writeRelationError(ctx, w, r, err)
+			return
+		}
+	}
+
+	// Обновляем только переданные колонки, время изменения и версию задачи;
+	// задача, измененная после чтения клиентом, не обновляется
+	assignments = append(assignments, "updated_at="+set.arg(now), "version=version+1")
+	query := "UPDATE tasks SET " + strings.Join(assignments, ", ") +
+		" WHERE id=" + set.arg(taskID) + " AND user_id=" + set.arg(currentUserID(r)) + " AND deleted_at IS NULL"
+	if expectedVersion != anyVersion {
+		query += " AND version=" + set.arg(expectedVersion)
+	}
+	query += " RETURNING " + taskColumns
+	var task models.Task
+	err = h.withTx(ctx, func(tx database.Tx) error {
+		// Запоминаем прежнее состояние задачи для журнала изменений
+		before, err := h.lockTask(ctx, tx, currentUserID(r), taskID)
+		if err != nil {
+			return err
+		}
+		if patchTags {
+			if err := h.attachTaskTags(ctx, &before); err != nil {
+				return err
+			}
+		}
+
+		if err := scanTask(tx.QueryRow(ctx, query, set.args...), &task); err != nil {
+			if err != sql.ErrNoRows {
+				h.logQueryError(ctx, err, query, set.args...)
+			}
+			return err
+		}
+
+		// Заменяем зависимости и метки задачи, если они были переданы
+		if patchDependencies {
+			if err := h.saveDependencies(ctx, tx, taskID, dependsOn); err != nil {
+				return err
+			}
+		}
+		if patchTags {
+			if err := h.saveTags(ctx, tx, currentUserID(r), taskID, tags); err != nil {
+				return err
+			}
+			task.Tags = tags
+		}
+		return h.audit(ctx, tx, currentUserID(r), taskID, models.AuditUpdated, auditDiff(&before, task))
+	})
+	if err == sql.ErrNoRows {
+		// Возвращаем 404 или 409 в зависимости от того, удалена задача или изменена
+		h.writeUpdateMiss(ctx, w, r, currentUserID(r), taskID)
+		return
+	}
+	if err != nil {
+		// Возвращаем ошибку сервера при сбое обновления
+		writeServerError(ctx, w, r, err, "Error updating task")
+		return
+	}
+	if patchDependencies {
+		task.DependsOn = dependsOn
+	}
+	if !patchTags {
+		if err := h.attachTaskTags(ctx, &task); err != nil {
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+	}
+
+	// Возвращаем обновленную задачу и ее новую версию в формате JSON
+	w.Header().Set("ETag", taskETag(task.Version))
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, task)
+}
@@ -0,0 +1,192 @@
+package hand
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// positionStep - расстояние между позициями соседних задач после перенумерации.
+// Должно совпадать с множителем в значении по умолчанию колонки position.
+const positionStep = 1024
+
+// moveRequest описывает тело запроса на перемещение задачи между соседями.
+// Отсутствующий PrevID означает перемещение в начало списка,
+// отсутствующий NextID - в конец.
+type moveRequest struct {
+	PrevID *int `json:"prev_id"`
+	NextID *int `json:"next_id"`
+}
+
+// neighborPositions возвращает текущие позиции задач пользователя userID с указанными ID.
+// Отсутствующие, удаленные и чужие задачи в результат не попадают.
+func (h *taskHandler) neighborPositions(ctx context.Context, userID int, ids []int) (map[int]float64, error) {
+	filter := &taskFilter{}
+	query := "SELECT id, position FROM tasks WHERE id IN (" + filter.argList(ids) + ") AND user_id = " + filter.arg(userID) + " AND deleted_at IS NULL"
+	rows, err := h.db.Query(ctx, query, filter.args...)
+	if err != nil {
+		h.logQueryError(ctx, err, query, filter.args...)
+		return nil, err
+	}
+	defer rows.Close()
+
+	positions := make(map[int]float64, len(ids))
+	for rows.Next() {
+		var id int
+		var position float64
+		if err := rows.Scan(&id, &position); err != nil {
+			return nil, err
+		}
+		positions[id] = position
+	}
+	return positions, rows.Err()
+}
+
+// rebalancePositions перенумеровывает позиции всех задач пользователя userID с шагом
+// positionStep, сохраняя их текущий порядок. Нужна, когда между соседними позициями
+// не осталось места для новой из-за ограниченной точности чисел.
+func (h *taskHandler) rebalancePositions(ctx context.Context, userID int) error {
+	query := `UPDATE tasks SET position = ordered.rank * ` + strconv.Itoa(positionStep) + `
+		FROM (SELECT id, row_number() OVER (ORDER BY position, id) AS rank FROM tasks WHERE user_id = $1) AS ordered
+		WHERE tasks.id = ordered.id`
+	if _, err := h.db.Exec(ctx, query, userID); err != nil {
+		h.logQueryError(ctx, err, query, userID)
+		return err
+	}
+	h.log(ctx).Info("Task positions rebalanced", "user_id", userID)
+	return nil
+}
+
+// MoveTaskBetween обрабатывает запрос на перемещение задачи между двумя соседями.
+// Тело {"prev_id": X, "next_id": Y} задает задачи, между которыми окажется перемещаемая;
+// без prev_id задача переносится в начало списка, без next_id - в конец.
+// Меняется только позиция перемещаемой задачи: она получает середину между позициями
+// соседей. Если середину нельзя представить из-за точности чисел, позиции всех задач
+// сначала перенумеровываются. Возвращает обновленную задачу в формате JSON.
+func (h *taskHandler) MoveTaskBetween(w http.ResponseWriter, r *http.Request) {
+	var req moveRequest
+	// Декодируем JSON-запрос в структуру req
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		// Возвращаем ошибку при некорректном запросе
+		writePayloadError(w, r, err)
+		return
+	}
+	if req.PrevID == nil && req.NextID == nil {
+		writeError(w, r, "At least one of prev_id and next_id is required", http.StatusBadRequest)
+		return
+	}
+
+	// Извлекаем ID задачи из параметров запроса
+	vars := mux.Vars(r)
+	taskID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		// Возвращаем ошибку при некорректном ID
+		writeError(w, r, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	// Задача не может быть соседом самой себя
+	var neighbors []int
+	for _, id := range []*int{req.PrevID, req.NextID} {
+		if id == nil {
+			continue
+		}
+		if *id == taskID {
+			writeError(w, r, "A task cannot be moved next to itself", http.StatusBadRequest)
+			return
+		}
+		neighbors = append(neighbors, *id)
+	}
+
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+	userID := currentUserID(r)
+
+	// Перемещение в конец берет новую позицию из последовательности, чтобы задача
+	// оставалась позади задач, создаваемых позже
+	set := &taskFilter{}
+	positionExpr := "nextval('tasks_position_seq') * " + strconv.Itoa(positionStep)
+	if req.NextID != nil {
+		// Вычисляем новую позицию; при нехватке точности перенумеровываем позиции
+		// и повторяем вычисление один раз
+		var position float64
+		for attempt := 0; ; attempt++ {
+			positions, err := h.neighborPositions(ctx, userID, neighbors)
+			if err != nil {
+				writeServerError(ctx, w, r, err, "Server error")
+				return
+			}
+			for _, id := range neighbors {
+				if _, ok := positions[id]; !ok {
+					writeError(w, r, fmt.Sprintf("Neighbor task %d not found", id), http.StatusBadRequest)
+					return
+				}
+			}
+
+			var fits bool
+			position, fits, err = midpoint(req, positions)
+			if err != nil {
+				writeError(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if fits {
+				break
+			}
+			if attempt > 0 {
+				writeServerError(ctx, w, r, err, "Server error")
+				return
+			}
+			if err := h.rebalancePositions(ctx, userID); err != nil {
+				writeServerError(ctx, w, r, err, "Server error")
+				return
+			}
+		}
+		positionExpr = set.arg(position)
+	}
+
+	// Обновляем позицию задачи, записываем изменение в журнал и получаем обновленную задачу
+	var task models.Task
+	query := "UPDATE tasks SET position=" + positionExpr + ", updated_at=" + set.arg(time.Now().Format(time.RFC3339)) + ", version=version+1" +
+		" WHERE id=" + set.arg(taskID) + " AND user_id=" + set.arg(userID) + " AND deleted_at IS NULL RETURNING " + taskColumns
+	args := set.args
+	task, err = h.updateTaskAudited(ctx, userID, taskID, query, args)
+	if err == sql.ErrNoRows {
+		// Возвращаем ошибку, если задача не найдена
+		writeError(w, r, "Task not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		// Возвращаем ошибку сервера при сбое обновления
+		writeServerError(ctx, w, r, err, "Error updating task")
+		return
+	}
+
+	// Возвращаем обновленную задачу в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, task)
+}
+
+// midpoint вычисляет позицию задачи между соседями из req по их позициям;
+// без prev_id позиция берется на шаг раньше next_id. fits=false означает,
+// что между соседями не осталось представимой позиции.
+func midpoint(req moveRequest, positions map[int]float64) (float64, bool, error) {
+	next := positions[*req.NextID]
+	if req.PrevID == nil {
+		return next - positionStep, true, nil
+	}
+
+	prev := positions[*req.PrevID]
+	if prev >= next {
+		return 0, false, fmt.Errorf("task %d must be positioned before task %d", *req.PrevID, *req.NextID)
+	}
+	middle := prev + (next-prev)/2
+	return middle, prev < middle && middle < next, nil
+}
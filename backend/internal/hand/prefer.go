@@ -0,0 +1,22 @@
+package hand
+
+import (
+	"net/http"
+	"strings"
+)
+
+// preferReturn возвращает значение предпочтения return из заголовка Prefer (RFC 7240),
+// например "minimal" для "Prefer: return=minimal", или пустую строку, если оно не указано.
+func preferReturn(r *http.Request) string {
+	for _, header := range r.Header.Values("Prefer") {
+		for _, preference := range strings.Split(header, ",") {
+			// Параметры предпочтения после ";" не используются
+			token, _, _ := strings.Cut(preference, ";")
+			name, value, found := strings.Cut(strings.TrimSpace(token), "=")
+			if found && strings.EqualFold(strings.TrimSpace(name), "return") {
+				return strings.ToLower(strings.Trim(strings.TrimSpace(value), `"`))
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+package hand
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+)
+
+// scoredTask - задача вместе с ее оценкой в списке /tasks/prioritized.
+type scoredTask struct {
+	models.Task
+	Score float64 `json:"score" xml:"score"`
+}
+
+// scoreExpression вычисляет оценку задачи в SQL. Аргументы: вес приоритета, вес срочности,
+// текущий момент (UTC) и горизонт срочности в секундах. Срочность равна 0 для задач
+// без срока или со сроком дальше горизонта, растет линейно до 1 к моменту срока
+// и продолжает расти у просроченных задач, но не выше 2 (просрочка на весь горизонт),
+// чтобы давно просроченные задачи с низким приоритетом не вытесняли все остальные.
+const scoreExpression = `ROUND((%[1]s::float8 * CASE priority WHEN 'high' THEN 3 WHEN 'medium' THEN 2 ELSE 1 END +
+	%[2]s::float8 * COALESCE(LEAST(2, GREATEST(0, 1 - EXTRACT(EPOCH FROM (due_date - %[3]s::timestamp))::float8 / %[4]s::float8)), 0))::numeric, 3)::float8`
+
+// GetPrioritizedTasks обрабатывает запрос на получение невыполненных задач, упорядоченных
+// по оценке: сумме взвешенного приоритета и взвешенной срочности срока выполнения
+// (веса задаются настройками PRIORITY_SCORE_WEIGHT, DUE_SCORE_WEIGHT и DUE_SCORE_HORIZON).
+// При равной оценке первыми идут задачи с более ранним сроком, затем - по ID.
+// Каждая задача возвращается вместе с оценкой. Поддерживает параметры limit и offset
+// с теми же значениями по умолчанию, что и GetTasks.
+func (h *taskHandler) GetPrioritizedTasks(w http.ResponseWriter, r *http.Request) {
+	// Разбираем параметры постраничного вывода
+	page, err := parsePagination(r)
+	if err != nil {
+		// Возвращаем ошибку при некорректных limit или offset
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	filter := &taskFilter{}
+	score := fmt.Sprintf(scoreExpression,
+		filter.arg(h.cfg.PriorityScoreWeight),
+		filter.arg(h.cfg.DueScoreWeight),
+		filter.arg(time.Now().UTC().Format(time.RFC3339)),
+		filter.arg(h.cfg.DueScoreHorizon.Seconds()))
+	filter.where("status <> " + filter.arg(models.StatusDone))
+	filter.where("user_id = " + filter.arg(currentUserID(r)))
+	filter.where("deleted_at IS NULL")
+	query := "SELECT " + taskColumns + ", " + score + " AS score FROM tasks" + filter.clause() +
+		" ORDER BY score DESC, due_date ASC NULLS LAST, id LIMIT " + filter.arg(page.limit) + " OFFSET " + filter.arg(page.offset)
+
+	// Выполняем запрос на выборку задач
+	rows, err := h.db.Query(ctx, query, filter.args...)
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, filter.args...)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	defer rows.Close()
+
+	tasks := []scoredTask{}
+	// Итерируем по результатам выборки: оценка следует за колонками задачи
+	for rows.Next() {
+		var task scoredTask
+		err := scanTask(scanFunc(func(dest ...interface{}) error {
+			return rows.Scan(append(dest, &task.Score)...)
+		}), &task.Task)
+		if err != nil {
+			// Возвращаем ошибку сервера при сбое сканирования
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+		tasks = append(tasks, task)
+	}
+
+	// Возвращаем задачи с оценками в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, tasks)
+}
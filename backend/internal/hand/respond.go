@@ -0,0 +1,131 @@
+package hand
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/NickolaiP/taskApi/backend/internal/logger"
+	"github.com/NickolaiP/taskApi/backend/internal/render"
+
+	"github.com/lib/pq"
+)
+
+// uniqueViolation - код ошибки PostgreSQL при нарушении ограничения уникальности.
+const uniqueViolation = "23505"
+
+// openTitleIndex - уникальный индекс заголовков открытых задач пользователя
+// (миграция 0023_task_open_title_unique.sql).
+const openTitleIndex = "tasks_user_open_title_idx"
+
+// isTitleConflict сообщает, что err - нарушение уникальности заголовка открытой задачи.
+func isTitleConflict(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == openTitleIndex
+}
+
+// writeResponse записывает v с кодом status в формате, который клиент предпочитает
+// по заголовку Accept запроса r (JSON или XML, см. пакет render). Ошибку записи
+// (например, если клиент закрыл соединение, не дочитав ответ) сообщить клиенту
+// уже нельзя, поэтому она только записывается в лог l.
+func writeResponse(l *logger.Logger, w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	if err := render.Write(w, r, status, v); err != nil {
+		l.Warn("Failed to write response", "error", err)
+	}
+}
+
+// writeError отвечает сообщением об ошибке message с кодом status
+// в формате, который клиент предпочитает по заголовку Accept запроса r.
+func writeError(w http.ResponseWriter, r *http.Request, message string, status int) {
+	render.Error(w, r, message, status)
+}
+
+// fieldMap - объект с заранее неизвестным набором полей, например изменившиеся поля
+// задачи. В XML каждое поле записывается элементом с именем поля в порядке имен,
+// так же как в JSON.
+type fieldMap[V any] map[string]V
+
+func (m fieldMap[V]) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := enc.EncodeElement(m[name], xml.StartElement{Name: xml.Name{Local: name}}); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// decodeStrict декодирует тело запроса body в v и отклоняет поля, которых нет в v:
+// иначе поле с опечаткой в имени молча терялось бы.
+func decodeStrict(body io.Reader, v interface{}) error {
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// unknownFieldPrefix - начало текста ошибки encoding/json о поле, отклоненном
+// DisallowUnknownFields; отдельного типа для этой ошибки пакет не определяет.
+const unknownFieldPrefix = "json: unknown field "
+
+// unknownField возвращает имя неизвестного поля из ошибки декодирования err.
+func unknownField(err error) (string, bool) {
+	quoted, ok := strings.CutPrefix(err.Error(), unknownFieldPrefix)
+	if !ok {
+		return "", false
+	}
+	if name, err := strconv.Unquote(quoted); err == nil {
+		return name, true
+	}
+	return quoted, true
+}
+
+// writePayloadError отвечает на ошибку декодирования тела запроса err: 413, если тело
+// превысило ограничение размера (middleware.LimitBody), иначе 400 - с именем поля,
+// если запрос содержит неизвестное поле.
+func writePayloadError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, r, fmt.Sprintf("Request body too large: limit is %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if name, ok := unknownField(err); ok {
+		writeError(w, r, fmt.Sprintf("Invalid request payload: unknown field %q", name), http.StatusBadRequest)
+		return
+	}
+	writeError(w, r, "Invalid request payload", http.StatusBadRequest)
+}
+
+// writeServerError отвечает на ошибку обработки запроса err. Если операция с базой данных
+// не уложилась в таймаут запроса (контекст ctx), отвечает 503 - клиент может повторить
+// запрос позже. Если у пользователя уже есть открытая задача с тем же заголовком,
+// отвечает 409, иначе 500 с сообщением message. lib/pq сообщает о прерванном
+// по таймауту запросе собственной ошибкой, поэтому проверяется и сам контекст.
+func writeServerError(ctx context.Context, w http.ResponseWriter, r *http.Request, err error, message string) {
+	status, message := serverError(ctx, err, message)
+	writeError(w, r, message, status)
+}
+
+// serverError возвращает код и сообщение ответа на ошибку err по правилам writeServerError.
+func serverError(ctx context.Context, err error, message string) (int, string) {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return http.StatusServiceUnavailable, "Request timed out"
+	}
+	if isTitleConflict(err) {
+		return http.StatusConflict, "Open task with this title already exists"
+	}
+	return http.StatusInternalServerError, message
+}
@@ -0,0 +1,45 @@
+package hand
+
+import (
+	"fmt"
+
+	"github.com/NickolaiP/taskApi/backend/internal/logger"
+)
+
+// maxLoggedArgLength - максимальная длина аргумента запроса в логе;
+// более длинные значения обрезаются, чтобы не раздувать логи описаниями задач.
+const maxLoggedArgLength = 64
+
+// logQueryError записывает в лог ошибку выполнения SQL-запроса вместе с текстом запроса.
+// Значения аргументов могут содержать персональные данные, поэтому они попадают
+// в лог только при logArgs (настройка LOG_SQL_ARGS), и то в сокращенном виде;
+// иначе записывается лишь их количество.
+func logQueryError(l *logger.Logger, logArgs bool, err error, query string, args ...interface{}) {
+	attrs := []interface{}{"error", err, "query", query, "args_count", len(args)}
+	if logArgs {
+		attrs = append(attrs, "args", sanitizeArgs(args))
+	}
+	l.Error("Database query failed", attrs...)
+}
+
+// sanitizeArgs приводит аргументы запроса к виду, безопасному для записи в лог:
+// длинные строки обрезаются, а двоичные данные заменяются их размером.
+func sanitizeArgs(args []interface{}) []string {
+	sanitized := make([]string, len(args))
+	for i, arg := range args {
+		var value string
+		switch v := arg.(type) {
+		case nil:
+			value = "NULL"
+		case []byte:
+			value = fmt.Sprintf("<%d bytes>", len(v))
+		default:
+			value = fmt.Sprintf("%v", v)
+		}
+		if runes := []rune(value); len(runes) > maxLoggedArgLength {
+			value = fmt.Sprintf("%s...(%d chars)", string(runes[:maxLoggedArgLength]), len(runes))
+		}
+		sanitized[i] = value
+	}
+	return sanitized
+}
@@ -0,0 +1,186 @@
+package hand
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// defaultCompletionRange - период статистики выполнения, если параметр from не указан.
+	defaultCompletionRange = 30 * 24 * time.Hour
+	// maxCompletionBuckets ограничивает количество интервалов в ответе статистики выполнения.
+	maxCompletionBuckets = 400
+)
+
+// bucketKeyLayout задает ключ интервала статистики: начало интервала по местным часам.
+const bucketKeyLayout = "2006-01-02T15:04:05"
+
+// completionBucket описывает количество созданных и выполненных задач за один интервал.
+// CompletionRate равен отношению выполненных задач к созданным и отсутствует (null),
+// если за интервал не создано ни одной задачи.
+type completionBucket struct {
+	Start          string   `json:"start" xml:"start"`
+	End            string   `json:"end" xml:"end"`
+	Created        int      `json:"created" xml:"created"`
+	Completed      int      `json:"completed" xml:"completed"`
+	CompletionRate *float64 `json:"completion_rate" xml:"completion_rate"`
+}
+
+// completionStats - ответ на запрос статистики выполнения задач.
+type completionStats struct {
+	Interval string             `json:"interval" xml:"interval"`
+	Timezone string             `json:"timezone" xml:"timezone"`
+	From     string             `json:"from" xml:"from"`
+	To       string             `json:"to" xml:"to"`
+	Buckets  []completionBucket `json:"buckets" xml:"buckets>bucket"`
+}
+
+// truncateInterval возвращает начало интервала interval (day, week или month),
+// содержащего момент t, в часовом поясе t. Неделя начинается с понедельника.
+func truncateInterval(t time.Time, interval string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch interval {
+	case "week":
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case "month":
+		return day.AddDate(0, 0, 1-day.Day())
+	default:
+		return day
+	}
+}
+
+// nextInterval возвращает начало интервала, следующего за интервалом с началом start.
+func nextInterval(start time.Time, interval string) time.Time {
+	switch interval {
+	case "week":
+		return start.AddDate(0, 0, 7)
+	case "month":
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// GetCompletionRate обрабатывает запрос на получение статистики выполнения задач во времени.
+// Для каждого интервала (параметр interval: day, week или month, по умолчанию week)
+// в периоде [from, to) возвращает количество созданных и выполненных задач.
+// Границы интервалов вычисляются в часовом поясе из параметра tz или в часовом поясе
+// по умолчанию; по умолчанию период охватывает последние 30 дней.
+func (h *taskHandler) GetCompletionRate(w http.ResponseWriter, r *http.Request) {
+	// Определяем часовой пояс, в котором считаются границы интервалов
+	location, err := h.requestLocation(r)
+	if err != nil {
+		// Возвращаем ошибку при неизвестном часовом поясе
+		writeError(w, r, "Invalid tz parameter", http.StatusBadRequest)
+		return
+	}
+
+	interval := r.URL.Query().Get("interval")
+	switch interval {
+	case "":
+		interval = "week"
+	case "day", "week", "month":
+	default:
+		// Возвращаем ошибку при неподдерживаемом интервале
+		writeError(w, r, "interval must be one of: day, week, month", http.StatusBadRequest)
+		return
+	}
+
+	// Разбираем границы периода; по умолчанию - последние 30 дней
+	from, err := parseTimeParam(r, "from", location)
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := parseTimeParam(r, "to", location)
+	if err != nil {
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if to.IsZero() {
+		to = time.Now().In(location)
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultCompletionRange)
+	}
+	if !from.Before(to) {
+		writeError(w, r, "from must be earlier than to", http.StatusBadRequest)
+		return
+	}
+
+	// Заранее строим все интервалы периода, чтобы интервалы без задач
+	// попали в ответ с нулевыми значениями
+	buckets := []completionBucket{}
+	index := map[string]int{}
+	for start := truncateInterval(from, interval); start.Before(to); start = nextInterval(start, interval) {
+		if len(buckets) == maxCompletionBuckets {
+			writeError(w, r, fmt.Sprintf("period is too long: at most %d intervals are allowed", maxCompletionBuckets), http.StatusBadRequest)
+			return
+		}
+		index[start.Format(bucketKeyLayout)] = len(buckets)
+		buckets = append(buckets, completionBucket{
+			Start: start.Format(time.RFC3339),
+			End:   nextInterval(start, interval).Format(time.RFC3339),
+		})
+	}
+
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Метки времени хранятся в UTC без часового пояса, поэтому сначала переводим их
+	// в местное время запроса и только затем округляем до начала интервала
+	query := `SELECT 'created', date_trunc($1, created_at AT TIME ZONE 'UTC' AT TIME ZONE $2) AS bucket, COUNT(*)
+			FROM tasks WHERE created_at >= $3 AND created_at < $4 AND user_id = $5 AND deleted_at IS NULL GROUP BY bucket
+		UNION ALL
+		SELECT 'completed', date_trunc($1, completed_at AT TIME ZONE 'UTC' AT TIME ZONE $2) AS bucket, COUNT(*)
+			FROM tasks WHERE completed_at >= $3 AND completed_at < $4 AND user_id = $5 AND deleted_at IS NULL GROUP BY bucket`
+	args := []interface{}{interval, location.String(), from.UTC(), to.UTC(), currentUserID(r)}
+	rows, err := h.db.Query(ctx, query, args...)
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, args...)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	defer rows.Close()
+
+	// Итерируем по результатам выборки и раскладываем счетчики по интервалам
+	for rows.Next() {
+		var kind string
+		var start time.Time
+		var count int
+		if err := rows.Scan(&kind, &start, &count); err != nil {
+			// Возвращаем ошибку сервера при сбое сканирования
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+		i, ok := index[start.Format(bucketKeyLayout)]
+		if !ok {
+			continue
+		}
+		if kind == "created" {
+			buckets[i].Created = count
+		} else {
+			buckets[i].Completed = count
+		}
+	}
+
+	for i := range buckets {
+		if buckets[i].Created > 0 {
+			rate := float64(buckets[i].Completed) / float64(buckets[i].Created)
+			buckets[i].CompletionRate = &rate
+		}
+	}
+
+	// Возвращаем статистику в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, completionStats{
+		Interval: interval,
+		Timezone: location.String(),
+		From:     from.Format(time.RFC3339),
+		To:       to.Format(time.RFC3339),
+		Buckets:  buckets,
+	})
+}
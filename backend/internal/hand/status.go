@@ -0,0 +1,78 @@
+package hand
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// CompleteTask обрабатывает запрос на отметку задачи с указанным ID выполненной.
+// Время выполнения уже выполненной задачи не меняется.
+// Возвращает обновленную задачу в формате JSON или 404, если задача не найдена.
+func (h *taskHandler) CompleteTask(w http.ResponseWriter, r *http.Request) {
+	h.setDone(w, r, true)
+}
+
+// IncompleteTask обрабатывает запрос на снятие с задачи с указанным ID отметки
+// о выполнении: выполненная задача возвращается в статус pending, а статус
+// невыполненной задачи (в том числе in_progress) не меняется.
+// Возвращает обновленную задачу в формате JSON или 404, если задача не найдена.
+func (h *taskHandler) IncompleteTask(w http.ResponseWriter, r *http.Request) {
+	h.setDone(w, r, false)
+}
+
+// setDone отмечает задачу из пути запроса выполненной или невыполненной, обновляя
+// статус, время выполнения и время изменения, и возвращает обновленную задачу в формате JSON.
+func (h *taskHandler) setDone(w http.ResponseWriter, r *http.Request, done bool) {
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Извлекаем ID задачи из параметров запроса
+	vars := mux.Vars(r)
+	taskID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		// Возвращаем ошибку при некорректном ID
+		writeError(w, r, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	// Время выполнения сохраняется, пока задача остается выполненной
+	query := "UPDATE tasks SET status=$1, completed_at=COALESCE(completed_at, $2::timestamp), updated_at=$2, version=version+1 WHERE id=$3 AND user_id=$4 AND deleted_at IS NULL RETURNING " + taskColumns
+	status := models.StatusDone
+	if !done {
+		query = "UPDATE tasks SET status=CASE WHEN status = 'done' THEN $1 ELSE status END, completed_at=NULL, updated_at=$2, version=version+1 WHERE id=$3 AND user_id=$4 AND deleted_at IS NULL RETURNING " + taskColumns
+		status = models.StatusPending
+	}
+
+	// Обновляем статус, записываем изменение в журнал и получаем обновленную задачу;
+	// отсутствие строки означает, что задачи нет или она удалена
+	var task models.Task
+	args := []interface{}{status, time.Now().Format(time.RFC3339), taskID, currentUserID(r)}
+	task, err = h.updateTaskAudited(ctx, currentUserID(r), taskID, query, args)
+	if err == sql.ErrNoRows {
+		// Возвращаем ошибку, если задача не найдена
+		writeError(w, r, "Task not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		// Возвращаем ошибку сервера при сбое обновления
+		writeServerError(ctx, w, r, err, "Error updating task")
+		return
+	}
+
+	// Добавляем к задаче ее метки
+	if err := h.attachTaskTags(ctx, &task); err != nil {
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+
+	// Возвращаем обновленную задачу в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, task)
+}
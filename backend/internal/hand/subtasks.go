@@ -0,0 +1,162 @@
+package hand
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// checkParent проверяет, что родительская задача parentID существует, не удалена
+// и принадлежит пользователю userID, а также что задача taskID не станет
+// собственным предком. Для новой задачи taskID равен 0: подзадач у нее еще нет,
+// поэтому цикл невозможен. Нулевой parentID означает задачу верхнего уровня.
+// Ошибки данных клиента возвращаются как *relationError, остальные - ошибки базы данных.
+func (h *taskHandler) checkParent(ctx context.Context, userID, taskID, parentID int) error {
+	if parentID == 0 {
+		return nil
+	}
+	if parentID == taskID {
+		return &relationError{http.StatusConflict, fmt.Sprintf("parent cycle: task %d cannot be its own parent", taskID)}
+	}
+
+	// Задачи других пользователей считаются несуществующими
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM tasks WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL)"
+	if err := h.db.QueryRow(ctx, query, parentID, userID).Scan(&exists); err != nil {
+		h.logQueryError(ctx, err, query, parentID, userID)
+		return err
+	}
+	if !exists {
+		return &relationError{http.StatusBadRequest, fmt.Sprintf("unknown parent task ID: %d", parentID)}
+	}
+
+	if taskID == 0 {
+		return nil
+	}
+
+	// Поднимаемся от нового родителя к корню: если среди предков есть сама задача,
+	// новая связь замкнет цикл
+	query = `WITH RECURSIVE ancestors(id) AS (
+			SELECT $1::integer
+			UNION
+			SELECT t.parent_id FROM tasks t JOIN ancestors a ON t.id = a.id WHERE t.parent_id IS NOT NULL
+		)
+		SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $2)`
+	var cycle bool
+	if err := h.db.QueryRow(ctx, query, parentID, taskID).Scan(&cycle); err != nil {
+		h.logQueryError(ctx, err, query, parentID, taskID)
+		return err
+	}
+	if cycle {
+		return &relationError{http.StatusConflict, fmt.Sprintf("parent cycle: task %d is an ancestor of task %d", taskID, parentID)}
+	}
+	return nil
+}
+
+// loadSubtasks возвращает неудаленные подзадачи задачи parentID в порядке
+// ручной сортировки.
+func (h *taskHandler) loadSubtasks(ctx context.Context, userID, parentID int) ([]models.Task, error) {
+	query := "SELECT " + taskColumns + " FROM tasks WHERE parent_id=$1 AND user_id=$2 AND deleted_at IS NULL ORDER BY position, id"
+	rows, err := h.db.Query(ctx, query, parentID, userID)
+	if err != nil {
+		h.logQueryError(ctx, err, query, parentID, userID)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		var task models.Task
+		if err := scanTask(rows, &task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+// attachSubtaskCounts заполняет количество неудаленных прямых подзадач задач tasks
+// одним запросом с группировкой по parent_id; у задач без подзадач количество равно 0.
+func (h *taskHandler) attachSubtaskCounts(ctx context.Context, userID int, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int, len(tasks))
+	ids := make([]int, len(tasks))
+	for i := range tasks {
+		index[tasks[i].ID] = i
+		ids[i] = tasks[i].ID
+		tasks[i].SubtaskCount = new(int)
+	}
+
+	filter := &taskFilter{}
+	query := "SELECT parent_id, COUNT(*) FROM tasks WHERE parent_id IN (" + filter.argList(ids) + ") AND user_id = " + filter.arg(userID) +
+		" AND deleted_at IS NULL GROUP BY parent_id"
+	rows, err := h.db.Query(ctx, query, filter.args...)
+	if err != nil {
+		h.logQueryError(ctx, err, query, filter.args...)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var parentID, count int
+		if err := rows.Scan(&parentID, &count); err != nil {
+			return err
+		}
+		*tasks[index[parentID]].SubtaskCount = count
+	}
+	return rows.Err()
+}
+
+// GetSubtasks обрабатывает запрос на получение подзадач задачи с указанным ID.
+// Возвращает 404, если задача не найдена, иначе массив подзадач в формате JSON.
+func (h *taskHandler) GetSubtasks(w http.ResponseWriter, r *http.Request) {
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Извлекаем ID задачи из параметров запроса
+	vars := mux.Vars(r)
+	taskID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		// Возвращаем ошибку при некорректном ID
+		writeError(w, r, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	// Проверяем, что задача существует
+	var exists int
+	userID := currentUserID(r)
+	query := "SELECT id FROM tasks WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL"
+	err = h.db.QueryRow(ctx, query, taskID, userID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		// Возвращаем ошибку, если задача не найдена
+		writeError(w, r, "Task not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, taskID, userID)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+
+	// Выбираем подзадачи задачи
+	tasks, err := h.loadSubtasks(ctx, userID, taskID)
+	if err != nil {
+		// Возвращаем ошибку сервера при сбое запроса
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+
+	// Возвращаем подзадачи в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, tasks)
+}
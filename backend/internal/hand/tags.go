@@ -0,0 +1,233 @@
+package hand
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/database"
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// queryErrorLogger записывает в лог ошибку SQL-запроса; такую сигнатуру имеют
+// методы logQueryError обработчиков.
+type queryErrorLogger func(ctx context.Context, err error, query string, args ...interface{})
+
+// saveTags заменяет метки задачи taskID пользователя userID. Отсутствующие у пользователя
+// метки создаются; пустой список удаляет все связи задачи с метками, а сами метки
+// остаются. Удаление старых связей и вставка новых должны выполняться в одной транзакции q.
+func (h *taskHandler) saveTags(ctx context.Context, q database.Querier, userID, taskID int, tags []string) error {
+	return saveTaskTags(ctx, q, h.logQueryError, userID, taskID, tags)
+}
+
+// saveTaskTags реализует saveTags для любого обработчика; ошибки запросов
+// записываются в лог через logQueryError.
+func saveTaskTags(ctx context.Context, q database.Querier, logQueryError queryErrorLogger, userID, taskID int, tags []string) error {
+	query := "DELETE FROM task_tags WHERE task_id=$1"
+	if _, err := q.Exec(ctx, query, taskID); err != nil {
+		logQueryError(ctx, err, query, taskID)
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	// Создаем недостающие метки одним запросом; существующие пропускаются
+	filter := &taskFilter{}
+	values := make([]string, len(tags))
+	for i, tag := range tags {
+		values[i] = "(" + filter.arg(userID) + ", " + filter.arg(tag) + ")"
+	}
+	query = "INSERT INTO tags (user_id, name) VALUES " + strings.Join(values, ", ") + " ON CONFLICT (user_id, name) DO NOTHING"
+	if _, err := q.Exec(ctx, query, filter.args...); err != nil {
+		logQueryError(ctx, err, query, filter.args...)
+		return err
+	}
+
+	// Связываем задачу со всеми ее метками
+	filter = &taskFilter{}
+	placeholders := make([]string, len(tags))
+	for i, tag := range tags {
+		placeholders[i] = filter.arg(tag)
+	}
+	query = "INSERT INTO task_tags (task_id, tag_id) SELECT " + filter.arg(taskID) + ", id FROM tags WHERE user_id = " + filter.arg(userID) +
+		" AND name IN (" + strings.Join(placeholders, ", ") + ")"
+	if _, err := q.Exec(ctx, query, filter.args...); err != nil {
+		logQueryError(ctx, err, query, filter.args...)
+		return err
+	}
+	return nil
+}
+
+// attachTags заполняет метки задач tasks одним запросом. Метки каждой задачи
+// упорядочены по имени; у задач без меток поле остается пустым.
+func (h *taskHandler) attachTags(ctx context.Context, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int, len(tasks))
+	ids := make([]int, len(tasks))
+	for i, task := range tasks {
+		index[task.ID] = i
+		ids[i] = task.ID
+	}
+
+	filter := &taskFilter{}
+	query := "SELECT tt.task_id, t.name FROM task_tags tt JOIN tags t ON t.id = tt.tag_id WHERE tt.task_id IN (" + filter.argList(ids) + ") ORDER BY t.name"
+	rows, err := h.db.Query(ctx, query, filter.args...)
+	if err != nil {
+		h.logQueryError(ctx, err, query, filter.args...)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID int
+		var name string
+		if err := rows.Scan(&taskID, &name); err != nil {
+			return err
+		}
+		task := &tasks[index[taskID]]
+		task.Tags = append(task.Tags, name)
+	}
+	return rows.Err()
+}
+
+// attachTaskTags заполняет метки одной задачи task.
+func (h *taskHandler) attachTaskTags(ctx context.Context, task *models.Task) error {
+	tasks := []models.Task{*task}
+	if err := h.attachTags(ctx, tasks); err != nil {
+		return err
+	}
+	task.Tags = tasks[0].Tags
+	return nil
+}
+
+// tagByFilterRequest - тело запроса на добавление метки задачам, подходящим под фильтр.
+type tagByFilterRequest struct {
+	Tag string `json:"tag"`
+}
+
+// tagByFilterResult - ответ на добавление метки по фильтру: количество задач,
+// получивших метку (задачи, у которых она уже была, не учитываются).
+type tagByFilterResult struct {
+	Tagged int `json:"tagged" xml:"tagged"`
+}
+
+// TagTasksByFilter обрабатывает запрос на добавление метки всем задачам, подходящим
+// под фильтр. Фильтр задается теми же параметрами запроса, что и у списка задач,
+// а метка - полем tag тела запроса. Хотя бы один параметр фильтра обязателен,
+// чтобы случайно не пометить все задачи. Связи с меткой добавляются одним запросом;
+// каждая задача, получившая метку, меняет версию и записывается в журнал изменений.
+func (h *taskHandler) TagTasksByFilter(w http.ResponseWriter, r *http.Request) {
+	location, err := h.requestLocation(r)
+	if err != nil {
+		// Возвращаем ошибку при неизвестном часовом поясе
+		writeError(w, r, "Invalid tz parameter", http.StatusBadRequest)
+		return
+	}
+	filter, err := parseTaskFilter(r, location)
+	if err != nil {
+		// Возвращаем ошибку при некорректных параметрах фильтра
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.criteria == 0 {
+		writeError(w, r, "At least one filter parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	var req tagByFilterRequest
+	// Декодируем JSON-запрос, отклоняя неизвестные поля
+	if err := decodeStrict(r.Body, &req); err != nil {
+		// Возвращаем ошибку при некорректном запросе
+		writePayloadError(w, r, err)
+		return
+	}
+	tag := models.NormalizeTag(req.Tag)
+	if tag == "" || len([]rune(tag)) > models.MaxTagLength {
+		h.writeValidationError(r.Context(), w, r, models.ValidationErrors{
+			"tag": fmt.Sprintf("tag must be between 1 and %d characters", models.MaxTagLength),
+		})
+		return
+	}
+
+	// Создаем контекст с таймаутом: фильтр может выбрать много задач
+	ctx, cancel := context.WithTimeout(r.Context(), max(h.queryTimeout, bulkQueryTimeout))
+	defer cancel()
+
+	userID := currentUserID(r)
+	now := time.Now().Format(time.RFC3339)
+	tagged := 0
+	err = h.withTx(ctx, func(tx database.Tx) error {
+		// Создаем метку, если ее еще нет у пользователя
+		query := "INSERT INTO tags (user_id, name) VALUES ($1, $2) ON CONFLICT (user_id, name) DO NOTHING"
+		if _, err := tx.Exec(ctx, query, userID, tag); err != nil {
+			h.logQueryError(ctx, err, query, userID, tag)
+			return err
+		}
+
+		// Добавляем связи с меткой и меняем версию помеченных задач. Основная команда
+		// не видит строк, вставленных в WITH, поэтому ARRAY возвращает метки задачи
+		// до изменения - они нужны журналу
+		query = "WITH tagged AS (INSERT INTO task_tags (task_id, tag_id) SELECT id, (SELECT id FROM tags WHERE user_id = " + filter.arg(userID) +
+			" AND name = " + filter.arg(tag) + ") FROM tasks" + filter.clause() + " ON CONFLICT DO NOTHING RETURNING task_id)" +
+			" UPDATE tasks SET updated_at = " + filter.arg(now) + ", version = version + 1 FROM tagged WHERE tasks.id = tagged.task_id" +
+			" RETURNING tasks.id, ARRAY(SELECT t.name FROM task_tags tt JOIN tags t ON t.id = tt.tag_id WHERE tt.task_id = tasks.id ORDER BY t.name)"
+		rows, err := tx.Query(ctx, query, filter.args...)
+		if err != nil {
+			h.logQueryError(ctx, err, query, filter.args...)
+			return err
+		}
+		type taggedTask struct {
+			id     int
+			change auditChange
+		}
+		var changes []taggedTask
+		for rows.Next() {
+			var taskID int
+			var before []string
+			if err := rows.Scan(&taskID, pq.Array(&before)); err != nil {
+				rows.Close()
+				return err
+			}
+			after := append(slices.Clone(before), tag)
+			sort.Strings(after)
+			// Как и при изменении задачи, пустой прежний список в журнал не записывается
+			change := auditChange{New: after}
+			if len(before) > 0 {
+				change.Old = before
+			}
+			changes = append(changes, taggedTask{id: taskID, change: change})
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		// Записываем изменение каждой задачи в журнал после чтения всех строк:
+		// транзакция не выполняет другие запросы, пока открыт результат
+		for _, task := range changes {
+			if err := h.audit(ctx, tx, userID, task.id, models.AuditUpdated, map[string]auditChange{"tags": task.change}); err != nil {
+				return err
+			}
+		}
+		tagged = len(changes)
+		return nil
+	})
+	if err != nil {
+		// Возвращаем ошибку сервера при сбое запроса
+		writeServerError(ctx, w, r, err, "Error tagging tasks")
+		return
+	}
+
+	// Возвращаем количество помеченных задач в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, tagByFilterResult{Tagged: tagged})
+}
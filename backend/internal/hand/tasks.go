@@ -1,12 +1,19 @@
 package hand
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"reflect"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/NickolaiP/taskApi/backend/internal/config"
 	"github.com/NickolaiP/taskApi/backend/internal/database"
 	"github.com/NickolaiP/taskApi/backend/internal/logger"
 	"github.com/NickolaiP/taskApi/backend/internal/models"
@@ -15,91 +22,625 @@ import (
 )
 
 // taskHandler представляет собой структуру обработчика для управления задачами.
-// Включает в себя подключение к базе данных и логгер.
+// Включает в себя подключение к базе данных, логгер, настройки приложения,
+// таймаут операций с базой данных и часовой пояс по умолчанию.
 type taskHandler struct {
-	db     database.Database
-	logger *logger.Logger
+	db           database.Database
+	logger       *logger.Logger
+	cfg          config.AppConfig
+	queryTimeout time.Duration
+	location     *time.Location
 }
 
-// NewTaskHandler создает новый экземпляр taskHandler с заданными базой данных и логгером.
-func NewTaskHandler(db database.Database, logger *logger.Logger) *taskHandler {
+// NewTaskHandler создает новый экземпляр taskHandler с заданными базой данных, логгером,
+// настройками приложения, таймаутом операций с базой данных на один запрос
+// и часовым поясом, который используется, если запрос не указал свой.
+func NewTaskHandler(db database.Database, logger *logger.Logger, cfg config.AppConfig, queryTimeout time.Duration, location *time.Location) *taskHandler {
 	return &taskHandler{
-		db:     db,
-		logger: logger,
+		db:           db,
+		logger:       logger,
+		cfg:          cfg,
+		queryTimeout: queryTimeout,
+		location:     location,
 	}
 }
 
+// taskColumns перечисляет колонки таблицы tasks в порядке, который ожидает scanTask.
+const taskColumns = "id, title, description, status, priority, blocked, blocked_reason, due_date, metadata, position, created_at, updated_at, completed_at, deleted_at, recurrence, recurrence_interval, recurred_from, remind_at, reminded, parent_id, version"
+
+// rowScanner описывает общий метод Scan у *sql.Row и *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanTask считывает строку, выбранную по taskColumns, в структуру задачи.
+// Отсутствующие срок выполнения, причина блокировки, время выполнения,
+// время удаления и время напоминания (NULL) превращаются в пустые строки, а отсутствующий
+// родитель - в нулевой ID. У неповторяющейся
+// задачи интервал повторения обнуляется, чтобы не попадать в ответ.
+func scanTask(row rowScanner, task *models.Task) error {
+	var dueDate, blockedReason, completedAt, deletedAt, recurrence, remindAt sql.NullString
+	var recurredFrom, parentID sql.NullInt64
+	var metadata []byte
+	if err := row.Scan(&task.ID, &task.Title, &task.Description, &task.Status, &task.Priority, &task.Blocked, &blockedReason,
+		&dueDate, &metadata, &task.Position, &task.CreatedAt, &task.UpdatedAt, &completedAt, &deletedAt,
+		&recurrence, &task.RecurrenceInterval, &recurredFrom, &remindAt, &task.Reminded, &parentID, &task.Version); err != nil {
+		return err
+	}
+	task.BlockedReason = blockedReason.String
+	task.DueDate = dueDate.String
+	task.Metadata = metadata
+	task.CompletedAt = completedAt.String
+	task.DeletedAt = deletedAt.String
+	task.Recurrence = recurrence.String
+	if task.Recurrence == "" {
+		task.RecurrenceInterval = 0
+	}
+	task.RecurredFrom = int(recurredFrom.Int64)
+	task.RemindAt = remindAt.String
+	task.ParentID = int(parentID.Int64)
+	return nil
+}
+
+// insertTaskInto - вставка новой задачи; аргументы запроса формирует insertTaskArgs.
+const insertTaskInto = "INSERT INTO tasks (title, description, status, priority, due_date, metadata, created_at, updated_at, completed_at, user_id, recurrence, recurrence_interval, remind_at, parent_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)"
+
+// insertTaskQuery вставляет новую задачу и возвращает присвоенные ей ID, позицию и версию.
+// Аргументы запроса формирует insertTaskArgs.
+const insertTaskQuery = insertTaskInto + " RETURNING id, position, version"
+
+// insertOpenTaskQuery вставляет новую задачу, как insertTaskQuery, только если у пользователя
+// нет открытой задачи с тем же заголовком; иначе запрос не возвращает строк.
+const insertOpenTaskQuery = insertTaskInto + " ON CONFLICT (user_id, title) WHERE deleted_at IS NULL AND status <> 'done' DO NOTHING RETURNING id, position, version"
+
+// insertTaskArgs возвращает аргументы insertTaskQuery для задачи task.
+func insertTaskArgs(task *models.Task) []interface{} {
+	return []interface{}{task.Title, task.Description, task.Status, task.Priority, dueDateArg(task.DueDate),
+		metadataArg(task.Metadata), task.CreatedAt, task.UpdatedAt, completedAtArg(task.CompletedAt), task.UserID,
+		recurrenceArg(task.Recurrence), recurrenceIntervalArg(task.RecurrenceInterval), remindAtArg(task.RemindAt), parentIDArg(task.ParentID)}
+}
+
+// recurrenceArg преобразует правило повторения задачи в аргумент запроса.
+// Пустое правило (задача не повторяется) сохраняется как NULL.
+func recurrenceArg(recurrence string) interface{} {
+	if recurrence == "" {
+		return nil
+	}
+	return recurrence
+}
+
+// recurrenceIntervalArg преобразует интервал повторения в аргумент запроса.
+// У неповторяющейся задачи сохраняется значение колонки по умолчанию.
+func recurrenceIntervalArg(interval int) int {
+	if interval <= 0 {
+		return 1
+	}
+	return interval
+}
+
+// requireAffected возвращает sql.ErrNoRows, если запрос result не затронул ни одной строки,
+// чтобы обработчик мог ответить 404 так же, как при выборке несуществующей задачи.
+func requireAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// dueDateArg преобразует срок выполнения задачи в аргумент запроса.
+// Пустой срок сохраняется как NULL, так как пустая строка не является
+// корректным значением TIMESTAMP.
+func dueDateArg(dueDate string) interface{} {
+	if dueDate == "" {
+		return nil
+	}
+	return dueDate
+}
+
+// remindAtArg преобразует время напоминания в аргумент запроса.
+// Пустое значение (напоминание не нужно) сохраняется как NULL.
+func remindAtArg(remindAt string) interface{} {
+	if remindAt == "" {
+		return nil
+	}
+	return remindAt
+}
+
+// parentIDArg преобразует ID родительской задачи в аргумент запроса.
+// Нулевой ID (задача верхнего уровня) сохраняется как NULL.
+func parentIDArg(parentID int) interface{} {
+	if parentID == 0 {
+		return nil
+	}
+	return parentID
+}
+
+// completedAtArg преобразует время выполнения задачи в аргумент запроса.
+// Пустое значение (задача не выполнена) сохраняется как NULL.
+func completedAtArg(completedAt string) interface{} {
+	if completedAt == "" {
+		return nil
+	}
+	return completedAt
+}
+
+// completionTime возвращает время выполнения задачи с новым статусом status.
+// Время сохраняется, пока задача остается выполненной, устанавливается в now
+// при переводе в статус done и сбрасывается при любом другом статусе.
+func completionTime(status string, previous models.Task, now string) string {
+	if status != models.StatusDone {
+		return ""
+	}
+	if previous.Status == models.StatusDone && previous.CompletedAt != "" {
+		return previous.CompletedAt
+	}
+	return now
+}
+
+// metadataArg преобразует метаданные задачи в аргумент запроса.
+// Пустые метаданные сохраняются как NULL, остальные передаются строкой,
+// так как драйвер кодирует []byte как bytea, а не как JSONB.
+func metadataArg(metadata json.RawMessage) interface{} {
+	if len(metadata) == 0 {
+		return nil
+	}
+	return string(metadata)
+}
+
+// log возвращает логгер запроса с контекстом ctx (с идентификатором запроса)
+// или общий логгер обработчика вне запроса.
+func (h *taskHandler) log(ctx context.Context) *logger.Logger {
+	return logger.FromContext(ctx, h.logger)
+}
+
+// logQueryError записывает в лог ошибку SQL-запроса с учетом настройки LOG_SQL_ARGS.
+func (h *taskHandler) logQueryError(ctx context.Context, err error, query string, args ...interface{}) {
+	logQueryError(h.log(ctx), h.cfg.LogSQLArgs, err, query, args...)
+}
+
+// validationErrorBody - ответ на ошибку проверки с сообщениями по полям.
+type validationErrorBody struct {
+	Errors models.ValidationErrors `json:"errors" xml:"errors"`
+}
+
+// writeValidationError отвечает 400 на ошибку проверки задачи. Ошибки по полям
+// (models.ValidationErrors) возвращаются в виде {"errors": {"поле": "сообщение"}},
+// остальные - текстом.
+func (h *taskHandler) writeValidationError(ctx context.Context, w http.ResponseWriter, r *http.Request, err error) {
+	fields, ok := err.(models.ValidationErrors)
+	if !ok {
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeResponse(h.log(ctx), w, r, http.StatusBadRequest, validationErrorBody{Errors: fields})
+}
+
+// checkDueDateRequired проверяет, что у задачи с приоритетом не ниже настройки
+// DUE_DATE_REQUIRED_PRIORITY задан срок выполнения. Задача уже прошла Validate,
+// а ее приоритет заполнен. Без настройки проверка не выполняется.
+func (h *taskHandler) checkDueDateRequired(task *models.Task) models.ValidationErrors {
+	threshold := h.cfg.DueDateRequiredPriority
+	if threshold == "" || task.DueDate != "" || !models.PriorityAtLeast(task.Priority, threshold) {
+		return nil
+	}
+	return models.ValidationErrors{
+		"due_date": fmt.Sprintf("due_date is required for tasks with %s priority", task.Priority),
+	}
+}
+
+// withTx выполняет fn в транзакции: фиксирует ее, если fn вернула nil,
+// и отменяет в противном случае. Возвращает ошибку fn или ошибку управления транзакцией.
+func (h *taskHandler) withTx(ctx context.Context, fn func(tx database.Tx) error) error {
+	tx, err := h.db.BeginTx(ctx)
+	if err != nil {
+		h.log(ctx).Error("Failed to begin transaction", "error", err)
+		return err
+	}
+	// После успешного Commit откат ничего не делает
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		h.log(ctx).Error("Failed to commit transaction", "error", err)
+		return err
+	}
+	return nil
+}
+
+// taskLocation возвращает адрес задачи id для заголовка Location и ссылок на созданные задачи.
+func taskLocation(id int) string {
+	return "/tasks/" + strconv.Itoa(id)
+}
+
+// requestLocation возвращает часовой пояс из параметра tz запроса
+// или часовой пояс сервиса по умолчанию, если параметр не указан.
+func (h *taskHandler) requestLocation(r *http.Request) (*time.Location, error) {
+	tz := r.URL.Query().Get("tz")
+	if tz == "" {
+		return h.location, nil
+	}
+	return time.LoadLocation(tz)
+}
+
+// titleFromDescription формирует заголовок задачи из первой непустой строки описания,
+// обрезая пробелы и ограничивая длину значением models.MaxTitleLength.
+func titleFromDescription(description string) string {
+	for _, line := range strings.Split(description, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		// Ограничиваем длину в символах, а не в байтах, чтобы не разрезать UTF-8
+		if runes := []rune(line); len(runes) > models.MaxTitleLength {
+			line = strings.TrimSpace(string(runes[:models.MaxTitleLength]))
+		}
+		return line
+	}
+	return ""
+}
+
+// prepareNewTask подготавливает новую задачу к сохранению: при включенной настройке
+// берет заголовок из описания, проверяет поля и заполняет значения по умолчанию.
+// Возвращает models.ValidationErrors, если задача некорректна.
+func (h *taskHandler) prepareNewTask(task *models.Task) error {
+	// Если включено в настройках, берем заголовок из описания,
+	// когда клиент прислал только описание
+	if h.cfg.TitleFromDescription && strings.TrimSpace(task.Title) == "" {
+		task.Title = titleFromDescription(task.Description)
+	}
+
+	if err := task.Validate(); err != nil {
+		return err
+	}
+
+	// Новые задачи без статуса считаются ожидающими выполнения,
+	// без приоритета - задачами среднего приоритета
+	if task.Status == "" {
+		task.Status = models.StatusPending
+	}
+	if task.Priority == "" {
+		task.Priority = models.PriorityMedium
+	}
+
+	// Блокировка устанавливается только отдельным запросом block,
+	// а признак отправленного напоминания - только при отправке
+	task.Blocked = false
+	task.BlockedReason = ""
+	task.Reminded = false
+	return nil
+}
+
+// setCreationTime устанавливает время создания и обновления новой задачи,
+// а для задачи, созданной сразу выполненной, - и время выполнения.
+func setCreationTime(task *models.Task, now string) {
+	task.CreatedAt = now
+	task.UpdatedAt = now
+	task.CompletedAt = completionTime(task.Status, models.Task{}, now)
+}
+
 // CreateTask обрабатывает запрос на создание новой задачи.
 // Декодирует тело запроса в структуру задачи, сохраняет задачу в базе данных
-// и возвращает созданную задачу в формате JSON.
+// и возвращает созданную задачу в формате JSON с адресом в заголовке Location.
+// Заголовок "Prefer: return=minimal" (или настройка CREATE_RETURN_MINIMAL)
+// отключает тело ответа, "Prefer: return=representation" - включает его.
 func (h *taskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 	var task models.Task
-	// Декодируем JSON-запрос в структуру task
-	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
+	// Декодируем JSON-запрос в структуру task, отклоняя неизвестные поля
+	if err := decodeStrict(r.Body, &task); err != nil {
 		// Возвращаем ошибку при некорректном запросе
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writePayloadError(w, r, err)
 		return
 	}
 
+	// Проверяем поля задачи до обращения к базе данных и заполняем значения по умолчанию
+	if err := h.prepareNewTask(&task); err != nil {
+		h.writeValidationError(r.Context(), w, r, err)
+		return
+	}
+	// Задача с высоким приоритетом без срока выполнения корректна по формату,
+	// но нарушает правило DUE_DATE_REQUIRED_PRIORITY, поэтому отклоняется с ответом 422
+	if errs := h.checkDueDateRequired(&task); errs != nil {
+		writeResponse(h.log(r.Context()), w, r, http.StatusUnprocessableEntity, validationErrorBody{Errors: errs})
+		return
+	}
+	task.DependsOn = uniqueIDs(task.DependsOn)
+	task.UserID = currentUserID(r)
+
 	// Создаем контекст с таймаутом для операции с базой данных
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
 	defer cancel()
 
+	// Проверяем, что задачи, от которых зависит новая задача, и ее родительская задача существуют
+	if err := h.checkDependencies(ctx, task.UserID, 0, task.DependsOn); err != nil {
+		writeRelationError(ctx, w, r, err)
+		return
+	}
+	if err := h.checkParent(ctx, task.UserID, 0, task.ParentID); err != nil {
+		writeRelationError(ctx, w, r, err)
+		return
+	}
+
 	// Устанавливаем время создания и обновления задачи
-	task.CreatedAt = time.Now().Format(time.RFC3339)
-	task.UpdatedAt = task.CreatedAt
+	setCreationTime(&task, time.Now().Format(time.RFC3339))
 
-	// Выполняем запрос на вставку новой задачи в базу данных и получаем её ID
-	err := h.db.QueryRow(ctx, "INSERT INTO tasks (title, description, due_date, created_at, updated_at) VALUES ($1, $2, $3, $4, $5) RETURNING id",
-		task.Title, task.Description, task.DueDate, task.CreatedAt, task.UpdatedAt).Scan(&task.ID)
+	// Вставляем задачу, ее зависимости и метки в одной транзакции,
+	// чтобы при сбое не осталась задача без зависимостей или меток
+	err := h.withTx(ctx, func(tx database.Tx) error {
+		// Выполняем запрос на вставку новой задачи в базу данных и получаем её ID и позицию
+		args := insertTaskArgs(&task)
+		if err := tx.QueryRow(ctx, insertTaskQuery, args...).Scan(&task.ID, &task.Position, &task.Version); err != nil {
+			h.logQueryError(ctx, err, insertTaskQuery, args...)
+			return err
+		}
+
+		// Сохраняем зависимости и метки новой задачи
+		if len(task.DependsOn) > 0 {
+			if err := h.saveDependencies(ctx, tx, task.ID, task.DependsOn); err != nil {
+				return err
+			}
+		}
+		if len(task.Tags) > 0 {
+			if err := h.saveTags(ctx, tx, task.UserID, task.ID, task.Tags); err != nil {
+				return err
+			}
+		}
+		return h.audit(ctx, tx, task.UserID, task.ID, models.AuditCreated, auditDiff(nil, task))
+	})
 	if err != nil {
 		// Возвращаем ошибку сервера, если вставка не удалась
-		http.Error(w, "Error creating task", http.StatusInternalServerError)
+		writeServerError(ctx, w, r, err, "Error creating task")
 		return
 	}
 
+	// Указываем адрес и версию созданной задачи
+	w.Header().Set("Location", taskLocation(task.ID))
+	w.Header().Set("ETag", taskETag(task.Version))
+
+	// Определяем, нужно ли возвращать тело ответа: по умолчанию это задается
+	// настройкой CREATE_RETURN_MINIMAL, а клиент может переопределить ее заголовком Prefer
+	minimal := h.cfg.CreateReturnMinimal
+	switch preference := preferReturn(r); preference {
+	case "minimal", "representation":
+		minimal = preference == "minimal"
+		w.Header().Set("Preference-Applied", "return="+preference)
+	}
+
 	// Устанавливаем статус ответа как Created и возвращаем созданную задачу
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(task)
+	if minimal {
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	writeResponse(h.log(r.Context()), w, r, http.StatusCreated, task)
 }
 
-// GetTasks обрабатывает запрос на получение списка всех задач.
+// taskList - ответ списка задач с метаданными страницы, если включена настройка ListEnvelope.
+type taskList struct {
+	Data       []models.Task `json:"data" xml:"data>task"`
+	Total      int           `json:"total" xml:"total"`
+	Limit      int           `json:"limit" xml:"limit"`
+	Offset     int           `json:"offset" xml:"offset"`
+	NextCursor string        `json:"next_cursor,omitempty" xml:"next_cursor,omitempty"`
+}
+
+// GetTasks обрабатывает запрос на получение списка задач.
+// Поддерживает поиск по ключевым словам в заголовке и описании (q, режим search_mode),
+// фильтрацию по статусу (status, можно указать несколько раз), по интервалу
+// срока выполнения (due_after, due_before, даты без времени - в часовом поясе tz),
+// по ключам метаданных через параметры вида metadata.key=value, по метке
+// (tag, можно указать несколько раз), сортировку
+// через параметр sort (id, position или priority) и постраничный вывод через параметры
+// limit и offset или limit и cursor. Курсор следующей страницы возвращается в заголовке
+// X-Next-Cursor; в отличие от offset, он не пропускает и не повторяет задачи,
+// если список меняется между запросами. Каждая задача возвращается вместе с метками. Общее количество
+// задач, удовлетворяющих фильтрам, возвращается в заголовке X-Total-Count, а при
+// включенной настройке ListEnvelope еще и в теле ответа (taskList). Удаленные задачи выводятся
+// только с параметром include_deleted=true. С параметром include=subtask_count
+// у каждой задачи возвращается количество ее подзадач (subtask_count).
 // Выполняет запрос к базе данных и возвращает задачи в формате JSON.
+// В режиме отладки параметр explain=true возвращает план выполнения запроса.
 func (h *taskHandler) GetTasks(w http.ResponseWriter, r *http.Request) {
+	// Определяем часовой пояс для дат без времени в параметрах фильтрации
+	location, err := h.requestLocation(r)
+	if err != nil {
+		// Возвращаем ошибку при неизвестном часовом поясе
+		writeError(w, r, "Invalid tz parameter", http.StatusBadRequest)
+		return
+	}
+
+	// Разбираем параметры фильтрации из строки запроса
+	filter, err := parseTaskFilter(r, location)
+	if err != nil {
+		// Возвращаем ошибку при некорректных параметрах фильтрации
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Разбираем параметры постраничного вывода
+	page, err := parsePagination(r)
+	if err != nil {
+		// Возвращаем ошибку при некорректных limit или offset
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Разбираем порядок сортировки; результаты полнотекстового поиска
+	// без явной сортировки упорядочиваются по релевантности
+	order, err := parseTaskSort(r)
+	if err != nil {
+		// Возвращаем ошибку при неизвестном порядке сортировки
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.relevance != "" && r.URL.Query().Get("sort") == "" {
+		order = filter.relevance + " DESC, id"
+	}
+
+	// Проверяем список вычисляемых данных, которые нужно включить в ответ
+	includeSubtaskCount := false
+	if include := r.URL.Query().Get("include"); include != "" {
+		if include != "subtask_count" {
+			writeError(w, r, "include must be subtask_count", http.StatusBadRequest)
+			return
+		}
+		includeSubtaskCount = true
+	}
+
+	// Разбираем курсор, который заменяет offset при постраничном выводе
+	sortName := taskSortName(r, filter)
+	cursor, err := parseTaskCursor(r, sortName)
+	if err != nil {
+		// Возвращаем ошибку при некорректном курсоре
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Создаем контекст с таймаутом для операции с базой данных
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
 	defer cancel()
 
-	// Выполняем запрос на выборку всех задач из базы данных
-	rows, err := h.db.Query(ctx, "SELECT id, title, description, due_date, created_at, updated_at FROM tasks")
+	// Запоминаем условия фильтров до добавления курсора и аргументов страницы,
+	// чтобы посчитать общее количество задач без LIMIT и OFFSET
+	where, whereArgs := filter.clause(), filter.args
+	if cursor != nil {
+		filter.where(cursor.condition(filter))
+	}
+
+	// Порядок сортировки всегда завершается ID, что делает страницы стабильными между запросами.
+	// Лишняя задача сверх limit показывает, что за страницей есть следующая
+	query := "SELECT " + taskColumns + " FROM tasks" + filter.clause() +
+		" ORDER BY " + order + " LIMIT " + filter.arg(page.limit+1) + " OFFSET " + filter.arg(page.offset)
+
+	// В режиме отладки по запросу возвращаем план выполнения вместо задач
+	if h.cfg.Debug && r.URL.Query().Get("explain") == "true" {
+		h.explainQuery(ctx, w, r, query, filter.args)
+		return
+	}
+
+	// Считаем общее количество задач, удовлетворяющих фильтрам
+	var total int
+	countQuery := "SELECT COUNT(*) FROM tasks" + where
+	if err := h.db.QueryRow(ctx, countQuery, whereArgs...).Scan(&total); err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, countQuery, whereArgs...)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+
+	// Выполняем запрос на выборку страницы задач, удовлетворяющих фильтрам
+	rows, err := h.db.Query(ctx, query, filter.args...)
 	if err != nil {
-		// Возвращаем ошибку сервера при сбое запроса
-		http.Error(w, "Server error", http.StatusInternalServerError)
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, filter.args...)
+		writeServerError(ctx, w, r, err, "Server error")
 		return
 	}
 	defer rows.Close()
 
-	var tasks []models.Task
+	// Инициализируем пустой срез, чтобы при отсутствии задач
+	// ответ кодировался как [], а не null
+	tasks := []models.Task{}
 	// Итерируем по результатам выборки и заполняем срез задач
 	for rows.Next() {
 		var task models.Task
-		if err := rows.Scan(&task.ID, &task.Title, &task.Description, &task.DueDate, &task.CreatedAt, &task.UpdatedAt); err != nil {
+		if err := scanTask(rows, &task); err != nil {
 			// Возвращаем ошибку сервера при сбое сканирования
-			http.Error(w, "Server error", http.StatusInternalServerError)
+			writeServerError(ctx, w, r, err, "Server error")
 			return
 		}
 		tasks = append(tasks, task)
 	}
 
-	// Возвращаем задачи в формате JSON
-	json.NewEncoder(w).Encode(tasks)
+	// Отбрасываем лишнюю задачу и выдаем курсор следующей страницы после последней задачи
+	var nextCursor string
+	if len(tasks) > page.limit {
+		tasks = tasks[:page.limit]
+		if sortName != "" {
+			nextCursor = nextTaskCursor(sortName, tasks[len(tasks)-1])
+		}
+	}
+
+	// Добавляем к задачам их метки и, по запросу, количество подзадач
+	if err := h.attachTags(ctx, tasks); err != nil {
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	if includeSubtaskCount {
+		if err := h.attachSubtaskCounts(ctx, currentUserID(r), tasks); err != nil {
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+	}
+
+	// Возвращаем общее количество задач, курсор и страницу задач в формате JSON
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if nextCursor != "" {
+		w.Header().Set("X-Next-Cursor", nextCursor)
+	}
+	if h.cfg.ListEnvelope {
+		writeResponse(h.log(r.Context()), w, r, http.StatusOK, taskList{Data: tasks, Total: total, Limit: page.limit, Offset: page.offset, NextCursor: nextCursor})
+		return
+	}
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, tasks)
+}
+
+// queryPlan - ответ с текстом запроса и планом его выполнения.
+type queryPlan struct {
+	Query string   `json:"query" xml:"query"`
+	Plan  []string `json:"plan" xml:"plan>line"`
+}
+
+// explainQuery выполняет EXPLAIN ANALYZE для запроса и возвращает текст запроса
+// и план его выполнения. Используется только в режиме отладки,
+// так как раскрывает структуру запросов и статистику базы данных.
+func (h *taskHandler) explainQuery(ctx context.Context, w http.ResponseWriter, r *http.Request, query string, args []interface{}) {
+	rows, err := h.db.Query(ctx, "EXPLAIN ANALYZE "+query, args...)
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, "EXPLAIN ANALYZE "+query, args...)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	defer rows.Close()
+
+	// Каждая строка результата EXPLAIN - одна строка плана
+	plan := []string{}
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			// Возвращаем ошибку сервера при сбое сканирования
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+		plan = append(plan, line)
+	}
+
+	// Возвращаем запрос и план его выполнения
+	writeResponse(h.log(ctx), w, r, http.StatusOK, queryPlan{Query: query, Plan: plan})
 }
 
 // GetTaskByID обрабатывает запрос на получение задачи по её ID.
-// Выполняет запрос к базе данных и возвращает задачу в формате JSON.
+// Выполняет запрос к базе данных и возвращает задачу вместе с метками в формате JSON.
+// С параметром include=subtasks в ответ добавляются подзадачи задачи.
+// Версия задачи возвращается в заголовке ETag.
 func (h *taskHandler) GetTaskByID(w http.ResponseWriter, r *http.Request) {
+	// Проверяем список связанных данных, которые нужно включить в ответ
+	includeSubtasks := false
+	if include := r.URL.Query().Get("include"); include != "" {
+		if include != "subtasks" {
+			writeError(w, r, "include must be subtasks", http.StatusBadRequest)
+			return
+		}
+		includeSubtasks = true
+	}
+
 	// Создаем контекст с таймаутом для операции с базой данных
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
 	defer cancel()
 
 	// Извлекаем ID задачи из параметров запроса
@@ -107,38 +648,189 @@ func (h *taskHandler) GetTaskByID(w http.ResponseWriter, r *http.Request) {
 	taskID, err := strconv.Atoi(vars["id"])
 	if err != nil {
 		// Возвращаем ошибку при некорректном ID
-		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		writeError(w, r, "Invalid task ID", http.StatusBadRequest)
 		return
 	}
 
 	var task models.Task
 	// Выполняем запрос на выборку задачи по ID
-	err = h.db.QueryRow(ctx, "SELECT id, title, description, due_date, created_at, updated_at FROM tasks WHERE id=$1", taskID).
-		Scan(&task.ID, &task.Title, &task.Description, &task.DueDate, &task.CreatedAt, &task.UpdatedAt)
-	if err != nil {
+	userID := currentUserID(r)
+	query := "SELECT " + taskColumns + " FROM tasks WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL"
+	err = scanTask(h.db.QueryRow(ctx, query, taskID, userID), &task)
+	if err == sql.ErrNoRows {
 		// Возвращаем ошибку, если задача не найдена
-		http.Error(w, "Task not found", http.StatusNotFound)
+		writeError(w, r, "Task not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, taskID, userID)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+
+	// Добавляем метки задачи
+	if err := h.attachTaskTags(ctx, &task); err != nil {
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+
+	// По запросу добавляем подзадачи; пустой список в ответе не выводится
+	if includeSubtasks {
+		task.Subtasks, err = h.loadSubtasks(ctx, userID, taskID)
+		if err != nil {
+			// Возвращаем ошибку сервера при сбое запроса
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+	}
+
+	// Возвращаем найденную задачу в формате JSON; ETag нужен клиенту для If-Match при изменении
+	w.Header().Set("ETag", taskETag(task.Version))
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, task)
+}
+
+// GetTasksByTitle обрабатывает запрос на поиск задач с точным совпадением заголовка.
+// Параметр title обязателен; при case_insensitive=true регистр символов не учитывается.
+// Так как заголовки не уникальны, всегда возвращает массив задач в формате JSON.
+func (h *taskHandler) GetTasksByTitle(w http.ResponseWriter, r *http.Request) {
+	// Извлекаем искомый заголовок из параметров запроса
+	title := r.URL.Query().Get("title")
+	if title == "" {
+		// Возвращаем ошибку, если заголовок не указан
+		writeError(w, r, "Missing title parameter", http.StatusBadRequest)
+		return
+	}
+
+	// Определяем, нужно ли сравнивать заголовки без учета регистра
+	caseInsensitive := false
+	if value := r.URL.Query().Get("case_insensitive"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			// Возвращаем ошибку при некорректном значении флага
+			writeError(w, r, "Invalid case_insensitive parameter", http.StatusBadRequest)
+			return
+		}
+		caseInsensitive = parsed
+	}
+
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Выбираем условие сравнения в зависимости от флага
+	query := "SELECT " + taskColumns + " FROM tasks WHERE title = $1 AND user_id = $2 AND deleted_at IS NULL ORDER BY id"
+	if caseInsensitive {
+		query = "SELECT " + taskColumns + " FROM tasks WHERE lower(title) = lower($1) AND user_id = $2 AND deleted_at IS NULL ORDER BY id"
+	}
+
+	// Выполняем запрос на выборку задач с указанным заголовком
+	userID := currentUserID(r)
+	rows, err := h.db.Query(ctx, query, title, userID)
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, title, userID)
+		writeServerError(ctx, w, r, err, "Server error")
 		return
 	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	// Итерируем по результатам выборки и заполняем срез задач
+	for rows.Next() {
+		var task models.Task
+		if err := scanTask(rows, &task); err != nil {
+			// Возвращаем ошибку сервера при сбое сканирования
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+		tasks = append(tasks, task)
+	}
+
+	// Возвращаем найденные задачи в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, tasks)
+}
+
+// autocompleteLimit - максимальное количество подсказок в ответе автодополнения.
+const autocompleteLimit = 10
+
+// AutocompleteTitles обрабатывает запрос на автодополнение заголовков задач.
+// Возвращает до autocompleteLimit различных заголовков, начинающихся с параметра prefix
+// без учета регистра, в формате JSON-массива строк.
+func (h *taskHandler) AutocompleteTitles(w http.ResponseWriter, r *http.Request) {
+	// Извлекаем префикс из параметров запроса
+	prefix := r.URL.Query().Get("prefix")
+	if prefix == "" {
+		// Возвращаем ошибку, если префикс не указан
+		writeError(w, r, "Missing prefix parameter", http.StatusBadRequest)
+		return
+	}
+
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Выполняем запрос на выборку заголовков по префиксу; условие на lower(title)
+	// использует индекс tasks_title_prefix_idx с text_pattern_ops
+	query := `SELECT DISTINCT title FROM tasks WHERE lower(title) LIKE lower($1) || '%' ESCAPE '\' AND user_id = $3 AND deleted_at IS NULL ORDER BY title LIMIT $2`
+	userID := currentUserID(r)
+	rows, err := h.db.Query(ctx, query, escapeLike(prefix), autocompleteLimit, userID)
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, prefix, autocompleteLimit, userID)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	defer rows.Close()
+
+	titles := []string{}
+	// Итерируем по результатам выборки и заполняем срез заголовков
+	for rows.Next() {
+		var title string
+		if err := rows.Scan(&title); err != nil {
+			// Возвращаем ошибку сервера при сбое сканирования
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+		titles = append(titles, title)
+	}
 
-	// Возвращаем найденную задачу в формате JSON
-	json.NewEncoder(w).Encode(task)
+	// Возвращаем подсказки в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, titles)
 }
 
 // UpdateTask обрабатывает запрос на обновление задачи по её ID.
 // Декодирует тело запроса, обновляет соответствующую запись в базе данных
-// и возвращает обновленную задачу в формате JSON.
+// и возвращает обновленную задачу в формате JSON. Так как PUT заменяет задачу целиком,
+// поля title и description обязательны. Заголовок If-Match с ETag задачи обязателен:
+// если задачу успели изменить, возвращается 409, и изменения другого клиента не теряются.
+// С заголовком "Prefer: return=changes" возвращает только изменившиеся поля.
 func (h *taskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
+	// Определяем версию задачи, которую изменяет клиент
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		writeIfMatchError(w, r, err)
+		return
+	}
+
 	var task models.Task
-	// Декодируем JSON-запрос в структуру task
-	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
+	// Декодируем JSON-запрос в структуру task, отклоняя неизвестные поля
+	if err := decodeStrict(r.Body, &task); err != nil {
 		// Возвращаем ошибку при некорректном запросе
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		writePayloadError(w, r, err)
+		return
+	}
+
+	// PUT заменяет задачу целиком, поэтому обязательные поля должны быть переданы,
+	// иначе запрос незаметно затрет их пустыми значениями; остальные поля проверяются
+	// так же, как при создании задачи
+	if err := task.Validate(); err != nil {
+		h.writeValidationError(r.Context(), w, r, err)
 		return
 	}
 
 	// Создаем контекст с таймаутом для операции с базой данных
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
 	defer cancel()
 
 	// Извлекаем ID задачи из параметров запроса
@@ -146,42 +838,356 @@ func (h *taskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 	taskID, err := strconv.Atoi(vars["id"])
 	if err != nil {
 		// Возвращаем ошибку при некорректном ID
-		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		writeError(w, r, "Invalid task ID", http.StatusBadRequest)
 		return
 	}
 
 	// Получаем существующую задачу для сохранения её поля CreatedAt
+	// и для сравнения с новым состоянием
 	var existingTask models.Task
-	err = h.db.QueryRow(ctx, "SELECT created_at FROM tasks WHERE id=$1", taskID).Scan(&existingTask.CreatedAt)
-	if err != nil {
+	userID := currentUserID(r)
+	query := "SELECT " + taskColumns + " FROM tasks WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL"
+	err = scanTask(h.db.QueryRow(ctx, query, taskID, userID), &existingTask)
+	if err == sql.ErrNoRows {
 		// Возвращаем ошибку, если задача не найдена
-		http.Error(w, "Task not found", http.StatusNotFound)
+		writeError(w, r, "Task not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, taskID, userID)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	if !versionMatches(expectedVersion, existingTask.Version) {
+		// Задачу изменили после того, как клиент ее прочитал
+		writeVersionConflict(w, r, existingTask.Version)
+		return
+	}
+	if err := h.attachTaskTags(ctx, &existingTask); err != nil {
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+
+	// Если статус или приоритет не переданы, сохраняем текущие значения задачи
+	if task.Status == "" {
+		task.Status = existingTask.Status
+	}
+	if task.Priority == "" {
+		task.Priority = existingTask.Priority
+	}
+	// Правило DUE_DATE_REQUIRED_PRIORITY проверяется по итоговому приоритету:
+	// PUT без due_date снимает срок и с задачи, приоритет которой не передан
+	if errs := h.checkDueDateRequired(&task); errs != nil {
+		writeResponse(h.log(r.Context()), w, r, http.StatusUnprocessableEntity, validationErrorBody{Errors: errs})
+		return
+	}
+
+	// Если зависимости переданы, проверяем их; без поля depends_on зависимости не меняются
+	task.DependsOn = uniqueIDs(task.DependsOn)
+	if err := h.checkDependencies(ctx, userID, taskID, task.DependsOn); err != nil {
+		writeRelationError(ctx, w, r, err)
+		return
+	}
+
+	// PUT заменяет и родительскую задачу: без parent_id задача становится задачей верхнего уровня
+	if err := h.checkParent(ctx, userID, taskID, task.ParentID); err != nil {
+		writeRelationError(ctx, w, r, err)
 		return
 	}
 
 	// Обновляем время изменения задачи
 	task.UpdatedAt = time.Now().Format(time.RFC3339)
+	task.CompletedAt = completionTime(task.Status, existingTask, task.UpdatedAt)
+
+	// Собираем новое состояние задачи для ответа и журнала изменений с сохранением
+	// оригинального поля CreatedAt; блокировка и позиция меняются только отдельными запросами
+	replaceTags := task.Tags != nil
+	task.CreatedAt = existingTask.CreatedAt
+	task.Position = existingTask.Position
+	task.Blocked = existingTask.Blocked
+	task.BlockedReason = existingTask.BlockedReason
+	task.RecurredFrom = existingTask.RecurredFrom
+	task.Reminded = existingTask.Reminded && sameTime(existingTask.RemindAt, task.RemindAt)
+	if !replaceTags {
+		task.Tags = existingTask.Tags
+	}
+	task.ID = taskID
+	task.Version = existingTask.Version + 1
+
+	// Обновляем задачу, ее зависимости и метки и записываем изменение в журнал в одной транзакции
+	err = h.withTx(ctx, func(tx database.Tx) error {
+		// Обновляем запись задачи в базе данных
+		query := "UPDATE tasks SET title=$1, description=$2, status=$3, priority=$4, due_date=$5, metadata=$6, updated_at=$7, completed_at=$8, recurrence=$11, recurrence_interval=$12, " +
+			"remind_at=$13, reminded=(reminded AND remind_at IS NOT DISTINCT FROM $13::timestamp), parent_id=$14, version=version+1 WHERE id=$9 AND user_id=$10 AND deleted_at IS NULL AND version=$15"
+		args := []interface{}{task.Title, task.Description, task.Status, task.Priority, dueDateArg(task.DueDate), metadataArg(task.Metadata), task.UpdatedAt, completedAtArg(task.CompletedAt), taskID, userID,
+			recurrenceArg(task.Recurrence), recurrenceIntervalArg(task.RecurrenceInterval), remindAtArg(task.RemindAt), parentIDArg(task.ParentID), existingTask.Version}
+		result, err := tx.Exec(ctx, query, args...)
+		if err != nil {
+			h.logQueryError(ctx, err, query, args...)
+			return err
+		}
+		// Задача могла быть удалена или изменена после проверки ее версии
+		if err := requireAffected(result); err != nil {
+			return err
+		}
 
-	// Обновляем запись задачи в базе данных
-	_, err = h.db.Exec(ctx, "UPDATE tasks SET title=$1, description=$2, due_date=$3, updated_at=$4 WHERE id=$5",
-		task.Title, task.Description, task.DueDate, task.UpdatedAt, taskID)
+		// Заменяем зависимости и метки задачи, если они были переданы
+		if task.DependsOn != nil {
+			if err := h.saveDependencies(ctx, tx, taskID, task.DependsOn); err != nil {
+				return err
+			}
+		}
+		if replaceTags {
+			if err := h.saveTags(ctx, tx, userID, taskID, task.Tags); err != nil {
+				return err
+			}
+		}
+		return h.audit(ctx, tx, userID, taskID, models.AuditUpdated, auditDiff(&existingTask, task))
+	})
+	if err == sql.ErrNoRows {
+		// Возвращаем 404 или 409 в зависимости от того, удалена задача или изменена
+		h.writeUpdateMiss(ctx, w, r, userID, taskID)
+		return
+	}
 	if err != nil {
 		// Возвращаем ошибку сервера при сбое обновления
-		http.Error(w, "Error updating task", http.StatusInternalServerError)
+		writeServerError(ctx, w, r, err, "Error updating task")
 		return
 	}
 
-	// Возвращаем обновленную задачу с сохранением оригинального поля CreatedAt
-	task.CreatedAt = existingTask.CreatedAt
-	task.ID = taskID
-	json.NewEncoder(w).Encode(task)
+	// Возвращаем обновленную задачу и ее новую версию
+	w.Header().Set("ETag", taskETag(task.Version))
+
+	// По запросу клиента возвращаем только изменившиеся поля
+	if preferReturn(r) == "changes" {
+		w.Header().Set("Preference-Applied", "return=changes")
+		writeResponse(h.log(r.Context()), w, r, http.StatusOK, changedFields(existingTask, task))
+		return
+	}
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, task)
+}
+
+// changedFields сравнивает прежнее и новое состояние задачи и возвращает
+// ID задачи вместе с новыми значениями только тех полей, которые изменились.
+func changedFields(before, after models.Task) fieldMap[interface{}] {
+	changes := fieldMap[interface{}]{"id": after.ID}
+	if before.Title != after.Title {
+		changes["title"] = after.Title
+	}
+	if before.Description != after.Description {
+		changes["description"] = after.Description
+	}
+	if before.Status != after.Status {
+		changes["status"] = after.Status
+	}
+	if before.Priority != after.Priority {
+		changes["priority"] = after.Priority
+	}
+	if before.Blocked != after.Blocked || before.BlockedReason != after.BlockedReason {
+		changes["blocked"] = after.Blocked
+		changes["blocked_reason"] = after.BlockedReason
+	}
+	if before.Position != after.Position {
+		changes["position"] = after.Position
+	}
+	if !sameTime(before.DueDate, after.DueDate) {
+		changes["due_date"] = after.DueDate
+	}
+	if !sameJSON(before.Metadata, after.Metadata) {
+		changes["metadata"] = after.Metadata
+	}
+	if before.UpdatedAt != after.UpdatedAt {
+		changes["updated_at"] = after.UpdatedAt
+	}
+	if before.Version != after.Version {
+		changes["version"] = after.Version
+	}
+	if !sameTime(before.CompletedAt, after.CompletedAt) {
+		changes["completed_at"] = after.CompletedAt
+	}
+	if !sameTime(before.RemindAt, after.RemindAt) {
+		changes["remind_at"] = after.RemindAt
+		changes["reminded"] = after.Reminded
+	}
+	if !slices.Equal(before.Tags, after.Tags) {
+		changes["tags"] = after.Tags
+	}
+	if before.ParentID != after.ParentID {
+		changes["parent_id"] = after.ParentID
+	}
+	if before.Recurrence != after.Recurrence || before.RecurrenceInterval != after.RecurrenceInterval {
+		changes["recurrence"] = after.Recurrence
+		changes["recurrence_interval"] = after.RecurrenceInterval
+	}
+	return changes
+}
+
+// sameTime сравнивает две метки времени в формате RFC3339 как моменты времени,
+// а если их не удается разобрать - как строки.
+func sameTime(a, b string) bool {
+	ta, errA := time.Parse(time.RFC3339Nano, a)
+	tb, errB := time.Parse(time.RFC3339Nano, b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return ta.Equal(tb)
+}
+
+// sameJSON сравнивает два JSON-значения по содержимому, без учета
+// форматирования и порядка ключей, который JSONB не сохраняет.
+func sameJSON(a, b json.RawMessage) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return bytes.Equal(a, b)
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// UpsertTaskByTitle обрабатывает запрос на создание или обновление задачи по заголовку.
+// Если у пользователя есть открытая (не удаленная и не выполненная) задача с заголовком
+// из пути, обновляет ее и отвечает 200, иначе создает новую задачу и отвечает 201.
+// Результат дублируется в заголовке X-Upsert-Result. Заголовок открытой задачи уникален,
+// поэтому одновременные запросы с одним заголовком не создают двух задач.
+// Переданные метки заменяют метки задачи; родителя и зависимости задают только запросы по ID задачи.
+func (h *taskHandler) UpsertTaskByTitle(w http.ResponseWriter, r *http.Request) {
+	var task models.Task
+	// Декодируем JSON-запрос в структуру task, отклоняя неизвестные поля
+	if err := decodeStrict(r.Body, &task); err != nil {
+		// Возвращаем ошибку при некорректном запросе
+		writePayloadError(w, r, err)
+		return
+	}
+
+	// Заголовок из пути имеет приоритет над заголовком из тела запроса;
+	// проверяем все поля задачи вместе с ним
+	task.Title = mux.Vars(r)["title"]
+	if err := task.Validate(); err != nil {
+		h.writeValidationError(r.Context(), w, r, err)
+		return
+	}
+	if task.ParentID != 0 || task.DependsOn != nil {
+		writeError(w, r, "parent_id and depends_on can only be set via /tasks/{id}", http.StatusBadRequest)
+		return
+	}
+
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Обновляем время изменения задачи
+	task.UpdatedAt = time.Now().Format(time.RFC3339)
+
+	// Создаем задачу, а если открытая задача с таким заголовком уже есть, обновляем ее;
+	// изменение записывается в журнал в той же транзакции.
+	// Если статус или приоритет не переданы, у существующей задачи они сохраняются.
+	// Время выполнения сохраняется, пока задача остается выполненной.
+	task.UserID = currentUserID(r)
+	replaceTags := task.Tags != nil
+	created := false
+	err := h.withTx(ctx, func(tx database.Tx) error {
+		// Вставка пропускается, если задача с таким заголовком уже есть,
+		// в том числе если ее одновременно создал другой запрос
+		candidate := task
+		if candidate.Status == "" {
+			candidate.Status = models.StatusPending
+		}
+		if candidate.Priority == "" {
+			candidate.Priority = models.PriorityMedium
+		}
+		setCreationTime(&candidate, task.UpdatedAt)
+		args := insertTaskArgs(&candidate)
+		err := tx.QueryRow(ctx, insertOpenTaskQuery, args...).Scan(&candidate.ID, &candidate.Position, &candidate.Version)
+		if err == nil {
+			created = true
+			task = candidate
+			if len(task.Tags) > 0 {
+				if err := h.saveTags(ctx, tx, task.UserID, task.ID, task.Tags); err != nil {
+					return err
+				}
+			}
+			return h.audit(ctx, tx, task.UserID, task.ID, models.AuditCreated, auditDiff(nil, task))
+		}
+		if err != sql.ErrNoRows {
+			h.logQueryError(ctx, err, insertOpenTaskQuery, args...)
+			return err
+		}
+
+		// Задача с таким заголовком есть - блокируем ее до конца транзакции и обновляем.
+		// sql.ErrNoRows здесь означает, что ее успели выполнить или удалить после вставки
+		var before models.Task
+		query := "SELECT " + taskColumns + " FROM tasks WHERE title=$1 AND user_id=$2 AND deleted_at IS NULL AND status <> 'done' FOR UPDATE"
+		if err := scanTask(tx.QueryRow(ctx, query, task.Title, task.UserID), &before); err != nil {
+			if err != sql.ErrNoRows {
+				h.logQueryError(ctx, err, query, task.Title, task.UserID)
+			}
+			return err
+		}
+		if err := h.attachTaskTags(ctx, &before); err != nil {
+			return err
+		}
+
+		query = `UPDATE tasks SET description=$2, status=COALESCE(NULLIF($3, ''), status), due_date=$4, metadata=$5, updated_at=$6,
+				completed_at=CASE WHEN COALESCE(NULLIF($3, ''), status) = 'done' THEN COALESCE(completed_at, $6::timestamp) END,
+				priority=COALESCE(NULLIF($7, ''), priority), recurrence=$9, recurrence_interval=$10,
+				remind_at=$11, reminded=(reminded AND remind_at IS NOT DISTINCT FROM $11::timestamp), version=version+1
+			WHERE id=$1 AND user_id=$8
+			RETURNING ` + taskColumns
+		args = []interface{}{before.ID, task.Description, task.Status, dueDateArg(task.DueDate), metadataArg(task.Metadata), task.UpdatedAt, task.Priority, task.UserID,
+			recurrenceArg(task.Recurrence), recurrenceIntervalArg(task.RecurrenceInterval), remindAtArg(task.RemindAt)}
+		if err := scanTask(tx.QueryRow(ctx, query, args...), &task); err != nil {
+			h.logQueryError(ctx, err, query, args...)
+			return err
+		}
+
+		// Метки заменяются, только если они переданы
+		if replaceTags {
+			if err := h.saveTags(ctx, tx, task.UserID, task.ID, task.Tags); err != nil {
+				return err
+			}
+		} else {
+			task.Tags = before.Tags
+		}
+		return h.audit(ctx, tx, task.UserID, task.ID, models.AuditUpdated, auditDiff(&before, task))
+	})
+	if err == sql.ErrNoRows {
+		// Найденную задачу изменил другой запрос - клиент может повторить свой
+		writeError(w, r, "Task was changed concurrently, retry the request", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		// Возвращаем ошибку сервера при сбое обновления или вставки
+		if created {
+			writeServerError(ctx, w, r, err, "Error creating task")
+		} else {
+			writeServerError(ctx, w, r, err, "Error updating task")
+		}
+		return
+	}
+	if !created {
+		// Задача найдена и обновлена
+		w.Header().Set("X-Upsert-Result", "updated")
+		writeResponse(h.log(r.Context()), w, r, http.StatusOK, task)
+		return
+	}
+
+	// Устанавливаем статус ответа как Created и возвращаем созданную задачу
+	w.Header().Set("X-Upsert-Result", "created")
+	writeResponse(h.log(r.Context()), w, r, http.StatusCreated, task)
 }
 
 // DeleteTask обрабатывает запрос на удаление задачи по её ID.
-// Выполняет запрос к базе данных для удаления задачи.
+// Удаление мягкое: задача помечается временем удаления и перестает попадать
+// в выборки, но может быть восстановлена запросом restore. Подзадачи удаляются
+// вместе с задачей или становятся задачами верхнего уровня (см. deleteTasks).
+// Возвращает 404, если задачи нет или она уже удалена, и 409, если удалять задачу
+// в ее статусе запрещено настройкой DELETABLE_STATUSES.
 func (h *taskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 	// Создаем контекст с таймаутом для операции с базой данных
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
 	defer cancel()
 
 	// Извлекаем ID задачи из параметров запроса
@@ -189,18 +1195,220 @@ func (h *taskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 	taskID, err := strconv.Atoi(vars["id"])
 	if err != nil {
 		// Возвращаем ошибку при некорректном ID
-		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		writeError(w, r, "Invalid task ID", http.StatusBadRequest)
 		return
 	}
 
-	// Выполняем запрос на удаление задачи по ID
-	_, err = h.db.Exec(ctx, "DELETE FROM tasks WHERE id=$1", taskID)
+	// Удаляем задачу; writeDeleteMiss отличит задачу в статусе, в котором удаление
+	// запрещено, от отсутствующей задачи
+	userID := currentUserID(r)
+	filter := &taskFilter{}
+	roots := "SELECT id FROM tasks WHERE id=" + filter.arg(taskID) + " AND user_id=" + filter.arg(userID) + " AND deleted_at IS NULL"
+	if statuses := h.cfg.DeletableStatuses; len(statuses) > 0 {
+		roots += " AND status IN (" + filter.argStrings(statuses) + ")"
+	}
+	deleted, err := h.deleteTasks(ctx, filter, roots, userID)
 	if err != nil {
 		// Возвращаем ошибку сервера при сбое удаления
-		http.Error(w, "Error deleting task", http.StatusInternalServerError)
+		writeServerError(ctx, w, r, err, "Error deleting task")
+		return
+	}
+	if deleted == 0 {
+		// Задача не найдена, уже удалена или находится в статусе, в котором удалять нельзя
+		h.writeDeleteMiss(ctx, w, r, userID, taskID)
 		return
 	}
 
 	// Устанавливаем статус ответа как No Content (204) при успешном удалении
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// writeDeleteMiss отвечает на удаление задачи taskID, не затронувшее ни одной строки:
+// 404, если у пользователя userID нет такой неудаленной задачи, иначе 409 - задача
+// находится в статусе, в котором удаление запрещено настройкой DELETABLE_STATUSES.
+func (h *taskHandler) writeDeleteMiss(ctx context.Context, w http.ResponseWriter, r *http.Request, userID, taskID int) {
+	var status string
+	query := "SELECT status FROM tasks WHERE id=$1 AND user_id=$2 AND deleted_at IS NULL"
+	err := h.db.QueryRow(ctx, query, taskID, userID).Scan(&status)
+	if err == sql.ErrNoRows {
+		// Возвращаем ошибку, если задача не найдена или уже удалена
+		writeError(w, r, "Task not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, taskID, userID)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	writeError(w, r, h.undeletableStatusMessage(status), http.StatusConflict)
+}
+
+// undeletableStatusMessage возвращает сообщение о том, что задачи в статусе status
+// удалять нельзя, со списком статусов, в которых удаление разрешено.
+func (h *taskHandler) undeletableStatusMessage(status string) string {
+	return fmt.Sprintf("Tasks in status %q cannot be deleted: allowed statuses are %s", status, strings.Join(h.cfg.DeletableStatuses, ", "))
+}
+
+// deleteTasks мягко удаляет задачи пользователя userID, выбранные запросом roots
+// (колонка id) с аргументами filter, и записывает удаление каждой задачи в журнал.
+// Все задачи удаляются одним временем, по которому restore найдет их вместе; уже
+// удаленные задачи не затрагиваются, поэтому у них сохраняется исходное время удаления.
+// Подзадачи обрабатываются по настройке SUBTASK_DELETE_MODE в той же транзакции:
+// удаляются вместе с задачей на любой глубине или становятся задачами верхнего уровня.
+// Возвращает количество удаленных задач.
+func (h *taskHandler) deleteTasks(ctx context.Context, filter *taskFilter, roots string, userID int) (int64, error) {
+	// Обе команды используют все аргументы filter, поэтому передают их целиком
+	now := filter.arg(time.Now().Format(time.RFC3339))
+	user := filter.arg(userID)
+	cascade := h.cfg.SubtaskDeleteMode != config.SubtaskDeleteOrphan
+
+	var deleted int64
+	err := h.withTx(ctx, func(tx database.Tx) error {
+		if !cascade {
+			// Отвязываем прямые подзадачи до удаления задач, пока roots их еще выбирает.
+			// Подзадачи, которые удаляются сами, не отвязываются
+			query := `WITH orphaned AS (
+					UPDATE tasks c SET parent_id=NULL, updated_at=` + now + `, version=c.version+1 FROM tasks p
+					WHERE c.parent_id = p.id AND p.id IN (` + roots + `) AND c.id NOT IN (` + roots + `) AND c.deleted_at IS NULL
+					RETURNING c.id, p.id AS parent_id
+				)
+				INSERT INTO task_audit (task_id, user_id, action, changes, created_at)
+				SELECT id, ` + user + `, '` + models.AuditUpdated + `', jsonb_build_object('parent_id', jsonb_build_object('old', parent_id)), ` + now + ` FROM orphaned`
+			if _, err := tx.Exec(ctx, query, filter.args...); err != nil {
+				h.logQueryError(ctx, err, query, filter.args...)
+				return err
+			}
+		}
+
+		// Выбираем удаляемые задачи: в каскадном режиме - вместе с подзадачами на любой глубине
+		subtree := roots
+		if cascade {
+			subtree = `WITH RECURSIVE subtree(id) AS (
+					` + roots + `
+					UNION
+					SELECT t.id FROM tasks t JOIN subtree s ON t.parent_id = s.id WHERE t.deleted_at IS NULL
+				)
+				SELECT id FROM subtree`
+		}
+		query := `WITH deleted AS (
+				UPDATE tasks SET deleted_at=` + now + ` WHERE id IN (` + subtree + `) AND deleted_at IS NULL RETURNING id
+			)
+			INSERT INTO task_audit (task_id, user_id, action, created_at) SELECT id, ` + user + `, '` + models.AuditDeleted + `', ` + now + ` FROM deleted`
+		result, err := tx.Exec(ctx, query, filter.args...)
+		if err != nil {
+			h.logQueryError(ctx, err, query, filter.args...)
+			return err
+		}
+		deleted, err = result.RowsAffected()
+		return err
+	})
+	return deleted, err
+}
+
+// deleteResult - ответ на удаление задач по фильтру.
+type deleteResult struct {
+	Deleted int64 `json:"deleted" xml:"deleted"`
+}
+
+// DeleteTasks обрабатывает запрос на удаление всех задач, подходящих под фильтр
+// (например, всех выполненных задач: status=done). Фильтр задается теми же
+// параметрами запроса, что и у списка задач; параметр status обязателен, чтобы
+// случайно не удалить все задачи, а сам запрос нужно подтвердить параметром
+// confirm=true. Удаление мягкое, а подзадачи обрабатываются так же, как у DeleteTask.
+// Возвращает количество удаленных задач.
+func (h *taskHandler) DeleteTasks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if len(query["status"]) == 0 {
+		writeError(w, r, "status filter is required to delete tasks", http.StatusBadRequest)
+		return
+	}
+	if confirmed, err := strconv.ParseBool(query.Get("confirm")); err != nil || !confirmed {
+		writeError(w, r, "confirm=true is required to delete tasks", http.StatusBadRequest)
+		return
+	}
+	if allowed := h.cfg.DeletableStatuses; len(allowed) > 0 {
+		for _, status := range query["status"] {
+			if models.IsValidStatus(status) && !slices.Contains(allowed, status) {
+				writeError(w, r, h.undeletableStatusMessage(status), http.StatusConflict)
+				return
+			}
+		}
+	}
+
+	location, err := h.requestLocation(r)
+	if err != nil {
+		// Возвращаем ошибку при неизвестном часовом поясе
+		writeError(w, r, "Invalid tz parameter", http.StatusBadRequest)
+		return
+	}
+	filter, err := parseTaskFilter(r, location)
+	if err != nil {
+		// Возвращаем ошибку при некорректных параметрах фильтра
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Создаем контекст с таймаутом: фильтр может выбрать много задач
+	ctx, cancel := context.WithTimeout(r.Context(), max(h.queryTimeout, bulkQueryTimeout))
+	defer cancel()
+
+	deleted, err := h.deleteTasks(ctx, filter, "SELECT id FROM tasks"+filter.clause(), currentUserID(r))
+	if err != nil {
+		// Возвращаем ошибку сервера при сбое удаления
+		writeServerError(ctx, w, r, err, "Error deleting tasks")
+		return
+	}
+
+	// Возвращаем количество удаленных задач в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, deleteResult{Deleted: deleted})
+}
+
+// RestoreTask обрабатывает запрос на восстановление удаленной задачи по её ID.
+// Вместе с задачей восстанавливаются подзадачи, удаленные вместе с ней. Возвращает восстановленную задачу в формате JSON или 404, если удаленной задачи
+// с таким ID нет.
+func (h *taskHandler) RestoreTask(w http.ResponseWriter, r *http.Request) {
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Извлекаем ID задачи из параметров запроса
+	vars := mux.Vars(r)
+	taskID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		// Возвращаем ошибку при некорректном ID
+		writeError(w, r, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	// Снимаем отметку об удалении с задачи и подзадач с тем же временем удаления
+	// и получаем восстановленную задачу; восстановление каждой задачи записывается
+	// в журнал изменений тем же запросом
+	var task models.Task
+	query := `WITH RECURSIVE subtree(id, deleted_at) AS (
+			SELECT id, deleted_at FROM tasks WHERE id=$2 AND user_id=$3 AND deleted_at IS NOT NULL
+			UNION
+			SELECT t.id, t.deleted_at FROM tasks t JOIN subtree s ON t.parent_id = s.id WHERE t.deleted_at = s.deleted_at
+		), restored AS (
+			UPDATE tasks SET deleted_at=NULL, updated_at=$1, version=version+1 WHERE id IN (SELECT id FROM subtree) RETURNING ` + taskColumns + `
+		), audited AS (
+			INSERT INTO task_audit (task_id, user_id, action, created_at) SELECT id, $3, '` + models.AuditRestored + `', $1 FROM restored
+		)
+		SELECT ` + taskColumns + ` FROM restored WHERE id=$2`
+	args := []interface{}{time.Now().Format(time.RFC3339), taskID, currentUserID(r)}
+	err = scanTask(h.db.QueryRow(ctx, query, args...), &task)
+	if err == sql.ErrNoRows {
+		// Возвращаем ошибку, если удаленная задача не найдена
+		writeError(w, r, "Deleted task not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое обновления
+		h.logQueryError(ctx, err, query, args...)
+		writeServerError(ctx, w, r, err, "Error restoring task")
+		return
+	}
+
+	// Возвращаем восстановленную задачу в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, task)
+}
@@ -0,0 +1,1013 @@
+package hand
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/auth"
+	"github.com/NickolaiP/taskApi/backend/internal/config"
+	"github.com/NickolaiP/taskApi/backend/internal/database/dbtest"
+	"github.com/NickolaiP/taskApi/backend/internal/logger"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// testUserID - пользователь, от имени которого выполняются запросы в тестах.
+const testUserID = 1
+
+// newTestTaskHandler создает обработчик задач поверх базы данных db в памяти.
+func newTestTaskHandler(db *dbtest.Mock) *taskHandler {
+	return NewTaskHandler(db, logger.InitLogger(io.Discard, "error", "text"), config.AppConfig{}, time.Second, time.UTC)
+}
+
+// newTestRequest создает запрос аутентифицированного пользователя testUserID
+// с параметрами маршрута vars.
+func newTestRequest(method, target, body string, vars map[string]string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r = r.WithContext(auth.WithUserID(r.Context(), testUserID))
+	return mux.SetURLVars(r, vars)
+}
+
+// taskRow возвращает строку задачи id с колонками taskColumns.
+func taskRow(id int64) []driver.Value {
+	return []driver.Value{id, "Купить молоко", "2 литра", "pending", "medium", false, nil,
+		nil, nil, int64(1), "2025-01-15T10:00:00Z", "2025-01-15T10:00:00Z", nil, nil,
+		nil, int64(1), nil, nil, false, nil, int64(3)}
+}
+
+func TestCreateTask(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		setup  func(db *dbtest.Mock)
+		status int
+	}{
+		{
+			name:   "malformed JSON",
+			body:   `{"title": `,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "unknown field",
+			body:   `{"title": "Купить молоко", "description": "2 литра", "owner": 2}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "missing description",
+			body:   `{"title": "Купить молоко"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "insert fails",
+			body: `{"title": "Купить молоко", "description": "2 литра"}`,
+			setup: func(db *dbtest.Mock) {
+				db.On("INSERT INTO tasks").Error(errors.New("connection reset"))
+			},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name: "audit fails",
+			body: `{"title": "Купить молоко", "description": "2 литра"}`,
+			setup: func(db *dbtest.Mock) {
+				db.On("INSERT INTO tasks").Rows([]string{"id", "position", "version"}, []driver.Value{int64(7), int64(1), int64(1)})
+				db.On("INSERT INTO task_audit").Error(errors.New("connection reset"))
+			},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name: "open task with the same title",
+			body: `{"title": "Купить молоко", "description": "2 литра"}`,
+			setup: func(db *dbtest.Mock) {
+				db.On("INSERT INTO tasks").Error(&pq.Error{Code: uniqueViolation, Constraint: openTitleIndex})
+			},
+			status: http.StatusConflict,
+		},
+		{
+			name: "created",
+			body: `{"title": "Купить молоко", "description": "2 литра"}`,
+			setup: func(db *dbtest.Mock) {
+				db.On("INSERT INTO tasks").Rows([]string{"id", "position", "version"}, []driver.Value{int64(7), int64(1), int64(1)})
+				db.On("INSERT INTO task_audit").Affected(1)
+			},
+			status: http.StatusCreated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New()
+			defer db.Close()
+			if tt.setup != nil {
+				tt.setup(db)
+			}
+
+			w := httptest.NewRecorder()
+			newTestTaskHandler(db).CreateTask(w, newTestRequest("POST", "/tasks", tt.body, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusBadRequest && len(db.Calls()) > 0 {
+				t.Errorf("invalid request reached the database: %v", db.Calls())
+			}
+			if tt.status == http.StatusCreated {
+				if got := w.Header().Get("Content-Type"); got != "application/json" {
+					t.Errorf("Content-Type = %q, want application/json", got)
+				}
+				if got := w.Header().Get("Location"); got != "/tasks/7" {
+					t.Errorf("Location = %q, want /tasks/7", got)
+				}
+				if args := db.Calls()[0].Args; args[9] != testUserID {
+					t.Errorf("task inserted for user %v, want %d", args[9], testUserID)
+				}
+			}
+		})
+	}
+}
+
+func TestGetTaskByID(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		id     string
+		setup  func(db *dbtest.Mock)
+		status int
+	}{
+		{
+			name:   "invalid ID",
+			target: "/tasks/abc",
+			id:     "abc",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "unknown include",
+			target: "/tasks/7?include=comments",
+			id:     "7",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "not found",
+			target: "/tasks/7",
+			id:     "7",
+			setup: func(db *dbtest.Mock) {
+				db.On("FROM tasks WHERE id=")
+			},
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "query fails",
+			target: "/tasks/7",
+			id:     "7",
+			setup: func(db *dbtest.Mock) {
+				db.On("FROM tasks WHERE id=").Error(errors.New("connection reset"))
+			},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name:   "tags query fails",
+			target: "/tasks/7",
+			id:     "7",
+			setup: func(db *dbtest.Mock) {
+				db.On("FROM tasks WHERE id=").Rows(strings.Split(taskColumns, ", "), taskRow(7))
+				db.On("FROM task_tags").Error(errors.New("connection reset"))
+			},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name:   "found",
+			target: "/tasks/7",
+			id:     "7",
+			setup: func(db *dbtest.Mock) {
+				db.On("FROM tasks WHERE id=").Rows(strings.Split(taskColumns, ", "), taskRow(7))
+				db.On("FROM task_tags").Rows([]string{"task_id", "name"}, []driver.Value{int64(7), "дом"})
+			},
+			status: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New()
+			defer db.Close()
+			if tt.setup != nil {
+				tt.setup(db)
+			}
+
+			w := httptest.NewRecorder()
+			newTestTaskHandler(db).GetTaskByID(w, newTestRequest("GET", tt.target, "", map[string]string{"id": tt.id}))
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusOK {
+				if got := w.Header().Get("ETag"); got != `"3"` {
+					t.Errorf("ETag = %q, want %q", got, `"3"`)
+				}
+				if body := w.Body.String(); !strings.Contains(body, `"tags":["дом"]`) {
+					t.Errorf("body %s does not contain task tags", body)
+				}
+			}
+		})
+	}
+}
+
+func TestUpsertTaskByTitle(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		setup  func(db *dbtest.Mock)
+		status int
+		result string
+	}{
+		{
+			name:   "missing description",
+			body:   `{"due_date": "2025-01-15T23:59:59Z"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "invalid tag",
+			body:   `{"description": "2 литра", "tags": [" "]}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "parent set by title",
+			body:   `{"description": "2 литра", "parent_id": 3}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "created",
+			body: `{"description": "2 литра", "tags": ["Дом"]}`,
+			setup: func(db *dbtest.Mock) {
+				db.On("ON CONFLICT (user_id, title)").Rows([]string{"id", "position", "version"}, []driver.Value{int64(7), int64(1), int64(1)})
+				db.On("DELETE FROM task_tags").Affected(0)
+				db.On("INSERT INTO tags").Affected(1)
+				db.On("INSERT INTO task_tags").Affected(1)
+				db.On("INSERT INTO task_audit").Affected(1)
+			},
+			status: http.StatusCreated,
+			result: "created",
+		},
+		{
+			name: "updated",
+			body: `{"description": "3 литра"}`,
+			setup: func(db *dbtest.Mock) {
+				db.On("ON CONFLICT (user_id, title)")
+				db.On("FOR UPDATE").Rows(strings.Split(taskColumns, ", "), taskRow(7))
+				db.On("FROM task_tags").Rows([]string{"task_id", "name"}, []driver.Value{int64(7), "дом"})
+				db.On("UPDATE tasks").Rows(strings.Split(taskColumns, ", "), taskRow(7))
+				db.On("INSERT INTO task_audit").Affected(1)
+			},
+			status: http.StatusOK,
+			result: "updated",
+		},
+		{
+			name: "task closed concurrently",
+			body: `{"description": "3 литра"}`,
+			setup: func(db *dbtest.Mock) {
+				db.On("ON CONFLICT (user_id, title)")
+				db.On("FOR UPDATE")
+			},
+			status: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New()
+			defer db.Close()
+			if tt.setup != nil {
+				tt.setup(db)
+			}
+
+			w := httptest.NewRecorder()
+			r := newTestRequest("PUT", "/tasks/by-title/Купить%20молоко", tt.body, map[string]string{"title": "Купить молоко"})
+			newTestTaskHandler(db).UpsertTaskByTitle(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusBadRequest && len(db.Calls()) > 0 {
+				t.Errorf("invalid request reached the database: %v", db.Calls())
+			}
+			if got := w.Header().Get("X-Upsert-Result"); got != tt.result {
+				t.Errorf("X-Upsert-Result = %q, want %q", got, tt.result)
+			}
+			if tt.result != "" && !strings.Contains(w.Body.String(), `"tags":["дом"]`) {
+				t.Errorf("body %s does not contain task tags", w.Body)
+			}
+		})
+	}
+}
+
+func TestUpdateTaskValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		errors []string
+	}{
+		{
+			name:   "missing fields",
+			body:   `{"status": "pending"}`,
+			errors: []string{`"title":"must not be empty"`, `"description":"must not be empty"`},
+		},
+		{
+			name:   "invalid fields",
+			body:   `{"title": "Купить молоко", "description": "2 литра", "status": "archived", "due_date": "завтра"}`,
+			errors: []string{`"status":`, `"due_date":`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New()
+			defer db.Close()
+
+			w := httptest.NewRecorder()
+			r := newTestRequest("PUT", "/tasks/7", tt.body, map[string]string{"id": "7"})
+			r.Header.Set("If-Match", `"3"`)
+			newTestTaskHandler(db).UpdateTask(w, r)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d; body: %s", w.Code, http.StatusBadRequest, w.Body)
+			}
+			for _, want := range tt.errors {
+				if !strings.Contains(w.Body.String(), want) {
+					t.Errorf("body %s does not contain %s", w.Body, want)
+				}
+			}
+			if len(db.Calls()) > 0 {
+				t.Errorf("invalid request reached the database: %v", db.Calls())
+			}
+		})
+	}
+}
+
+func TestDueDateRequiredPriority(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold string
+		update    bool
+		body      string
+		status    int
+	}{
+		{
+			name:   "relaxed without the setting",
+			body:   `{"title": "Купить молоко", "description": "2 литра", "priority": "high"}`,
+			status: http.StatusCreated,
+		},
+		{
+			name:      "high priority without due date",
+			threshold: "high",
+			body:      `{"title": "Купить молоко", "description": "2 литра", "priority": "high"}`,
+			status:    http.StatusUnprocessableEntity,
+		},
+		{
+			name:      "default priority at the threshold",
+			threshold: "medium",
+			body:      `{"title": "Купить молоко", "description": "2 литра"}`,
+			status:    http.StatusUnprocessableEntity,
+		},
+		{
+			name:      "priority below the threshold",
+			threshold: "medium",
+			body:      `{"title": "Купить молоко", "description": "2 литра", "priority": "low"}`,
+			status:    http.StatusCreated,
+		},
+		{
+			name:      "high priority with due date",
+			threshold: "high",
+			body:      `{"title": "Купить молоко", "description": "2 литра", "priority": "high", "due_date": "2025-01-20T18:00:00Z"}`,
+			status:    http.StatusCreated,
+		},
+		{
+			name:   "update relaxed without the setting",
+			update: true,
+			body:   `{"title": "Купить молоко", "description": "2 литра", "priority": "high"}`,
+			status: http.StatusOK,
+		},
+		{
+			name:      "update to high priority without due date",
+			threshold: "high",
+			update:    true,
+			body:      `{"title": "Купить молоко", "description": "2 литра", "priority": "high"}`,
+			status:    http.StatusUnprocessableEntity,
+		},
+		{
+			name:      "update keeping priority at the threshold",
+			threshold: "medium",
+			update:    true,
+			body:      `{"title": "Купить молоко", "description": "2 литра"}`,
+			status:    http.StatusUnprocessableEntity,
+		},
+		{
+			name:      "update with due date",
+			threshold: "medium",
+			update:    true,
+			body:      `{"title": "Купить молоко", "description": "2 литра", "due_date": "2025-01-20T18:00:00Z"}`,
+			status:    http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New()
+			defer db.Close()
+			db.On("INSERT INTO tasks").Rows([]string{"id", "position", "version"}, []driver.Value{int64(7), int64(1), int64(1)})
+			db.On("UPDATE tasks").Affected(1)
+			db.On("INSERT INTO task_audit").Affected(1)
+			db.On("FROM task_tags")
+			db.On("FROM tasks WHERE id=$1").Rows(strings.Split(taskColumns, ", "), taskRow(7))
+
+			h := newTestTaskHandler(db)
+			h.cfg.DueDateRequiredPriority = tt.threshold
+			w := httptest.NewRecorder()
+			if tt.update {
+				r := newTestRequest("PUT", "/tasks/7", tt.body, map[string]string{"id": "7"})
+				r.Header.Set("If-Match", `"3"`)
+				h.UpdateTask(w, r)
+			} else {
+				h.CreateTask(w, newTestRequest("POST", "/tasks", tt.body, nil))
+			}
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusUnprocessableEntity {
+				return
+			}
+			if !strings.Contains(w.Body.String(), `"due_date":"due_date is required`) {
+				t.Errorf("body %s does not contain the due_date error", w.Body)
+			}
+			for _, call := range db.Calls() {
+				if strings.HasPrefix(call.Query, "INSERT") || strings.HasPrefix(call.Query, "UPDATE") {
+					t.Errorf("rejected task was saved: %s", call.Query)
+				}
+			}
+		})
+	}
+}
+
+func TestXMLResponses(t *testing.T) {
+	tests := []struct {
+		name   string
+		serve  func(h *taskHandler, w http.ResponseWriter, r *http.Request)
+		method string
+		body   string
+		setup  func(db *dbtest.Mock)
+		status int
+		want   []string
+	}{
+		{
+			name:   "task",
+			serve:  (*taskHandler).GetTaskByID,
+			method: "GET",
+			setup: func(db *dbtest.Mock) {
+				db.On("FROM tasks WHERE id=").Rows(strings.Split(taskColumns, ", "), taskRow(7))
+				db.On("FROM task_tags").Rows([]string{"task_id", "name"}, []driver.Value{int64(7), "дом"})
+			},
+			status: http.StatusOK,
+			want:   []string{"<response><id>7</id><title>Купить молоко</title>", "<tags><tag>дом</tag></tags>"},
+		},
+		{
+			name:   "error",
+			serve:  (*taskHandler).GetTaskByID,
+			method: "GET",
+			setup: func(db *dbtest.Mock) {
+				db.On("FROM tasks WHERE id=")
+			},
+			status: http.StatusNotFound,
+			want:   []string{"<error>Task not found</error>"},
+		},
+		{
+			name:   "validation errors",
+			serve:  (*taskHandler).CreateTask,
+			method: "POST",
+			body:   `{"title": "Купить молоко"}`,
+			status: http.StatusBadRequest,
+			want:   []string{"<response><errors><description>must not be empty</description></errors></response>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New()
+			defer db.Close()
+			if tt.setup != nil {
+				tt.setup(db)
+			}
+
+			w := httptest.NewRecorder()
+			r := newTestRequest(tt.method, "/tasks/7", tt.body, map[string]string{"id": "7"})
+			r.Header.Set("Accept", "application/xml")
+			tt.serve(newTestTaskHandler(db), w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body: %s", w.Code, tt.status, w.Body)
+			}
+			if got := w.Header().Get("Content-Type"); got != "application/xml; charset=utf-8" {
+				t.Errorf("Content-Type = %q, want application/xml", got)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(w.Body.String(), want) {
+					t.Errorf("body %s does not contain %s", w.Body, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetTasksEmpty(t *testing.T) {
+	tests := []struct {
+		name     string
+		envelope bool
+		want     string
+	}{
+		{
+			name: "array",
+			want: "[]\n",
+		},
+		{
+			name:     "envelope",
+			envelope: true,
+			want:     `{"data":[],"total":0,"limit":` + strconv.Itoa(defaultPageLimit) + `,"offset":0}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New()
+			defer db.Close()
+			db.On("SELECT COUNT(*) FROM tasks").Rows([]string{"count"}, []driver.Value{int64(0)})
+			db.On("FROM tasks").Rows(strings.Split(taskColumns, ", "))
+
+			h := newTestTaskHandler(db)
+			h.cfg.ListEnvelope = tt.envelope
+			w := httptest.NewRecorder()
+			h.GetTasks(w, newTestRequest("GET", "/tasks", "", nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d; body: %s", w.Code, http.StatusOK, w.Body)
+			}
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+			if got := w.Header().Get("X-Total-Count"); got != "0" {
+				t.Errorf("X-Total-Count = %q, want 0", got)
+			}
+		})
+	}
+}
+
+func TestGetTasksSubtaskCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		status int
+		want   []string
+	}{
+		{
+			name:   "unknown include",
+			target: "/tasks?include=subtasks",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "without counts",
+			target: "/tasks",
+			status: http.StatusOK,
+		},
+		{
+			name:   "with counts",
+			target: "/tasks?include=subtask_count",
+			status: http.StatusOK,
+			want:   []string{`"id":7,`, `"subtask_count":2`, `"id":8,`, `"subtask_count":0`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New()
+			defer db.Close()
+			db.On("GROUP BY parent_id").Rows([]string{"parent_id", "count"}, []driver.Value{int64(7), int64(2)})
+			db.On("SELECT COUNT(*) FROM tasks").Rows([]string{"count"}, []driver.Value{int64(2)})
+			db.On("FROM task_tags").Rows([]string{"task_id", "name"})
+			db.On("FROM tasks").Rows(strings.Split(taskColumns, ", "), taskRow(7), taskRow(8))
+
+			w := httptest.NewRecorder()
+			newTestTaskHandler(db).GetTasks(w, newTestRequest("GET", tt.target, "", nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body: %s", w.Code, tt.status, w.Body)
+			}
+			body := w.Body.String()
+			if tt.status == http.StatusOK && tt.want == nil && strings.Contains(body, "subtask_count") {
+				t.Errorf("body %s contains subtask counts without include", body)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("body %s does not contain %s", body, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateTasksBulkPartial(t *testing.T) {
+	// Первая задача корректна, у второй нет описания
+	const body = `[{"title": "Купить молоко", "description": "2 литра"}, {"title": "Купить хлеб"}]`
+
+	tests := []struct {
+		name     string
+		target   string
+		setup    func(db *dbtest.Mock)
+		status   int
+		statuses []int
+	}{
+		{
+			name:   "invalid partial parameter",
+			target: "/tasks/bulk?partial=maybe",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "atomic mode rejects the whole batch",
+			target: "/tasks/bulk",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "partial mode creates the valid task",
+			target: "/tasks/bulk?partial=true",
+			setup: func(db *dbtest.Mock) {
+				db.On("INSERT INTO tasks").Rows([]string{"id", "position", "version"}, []driver.Value{int64(7), int64(1), int64(1)})
+				db.On("INSERT INTO task_audit").Affected(1)
+			},
+			status:   http.StatusMultiStatus,
+			statuses: []int{http.StatusCreated, http.StatusBadRequest},
+		},
+		{
+			name:   "partial mode reports insert errors per task",
+			target: "/tasks/bulk?partial=true",
+			setup: func(db *dbtest.Mock) {
+				db.On("INSERT INTO tasks").Error(&pq.Error{Code: uniqueViolation, Constraint: openTitleIndex})
+			},
+			status:   http.StatusMultiStatus,
+			statuses: []int{http.StatusConflict, http.StatusBadRequest},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New()
+			defer db.Close()
+			if tt.setup != nil {
+				tt.setup(db)
+			}
+
+			w := httptest.NewRecorder()
+			newTestTaskHandler(db).CreateTasksBulk(w, newTestRequest("POST", tt.target, body, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusBadRequest {
+				if len(db.Calls()) > 0 {
+					t.Errorf("rejected batch reached the database: %v", db.Calls())
+				}
+				return
+			}
+
+			var got bulkResultBody
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if len(got.Results) != len(tt.statuses) {
+				t.Fatalf("results = %+v, want %d items", got.Results, len(tt.statuses))
+			}
+			for i, result := range got.Results {
+				if result.Index != i || result.Status != tt.statuses[i] {
+					t.Errorf("result %d = index %d status %d, want index %d status %d", i, result.Index, result.Status, i, tt.statuses[i])
+				}
+				if (result.Task != nil) != (result.Status == http.StatusCreated) {
+					t.Errorf("result %d: task = %+v with status %d", i, result.Task, result.Status)
+				}
+				if result.Task != nil && result.Task.Location != "/tasks/7" {
+					t.Errorf("result %d: location = %q, want /tasks/7", i, result.Task.Location)
+				}
+			}
+		})
+	}
+}
+
+func TestGetPrioritizedTasks(t *testing.T) {
+	db := dbtest.New()
+	defer db.Close()
+	db.On("AS score").Rows(append(strings.Split(taskColumns, ", "), "score"),
+		append(taskRow(7), 5.5), append(taskRow(8), 2.0))
+
+	h := newTestTaskHandler(db)
+	h.cfg.PriorityScoreWeight = 1
+	h.cfg.DueScoreWeight = 3
+	h.cfg.DueScoreHorizon = 7 * 24 * time.Hour
+	w := httptest.NewRecorder()
+	h.GetPrioritizedTasks(w, newTestRequest("GET", "/tasks/prioritized", "", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var got []struct {
+		ID    int     `json:"id"`
+		Score float64 `json:"score"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != 7 || got[0].Score != 5.5 || got[1].ID != 8 || got[1].Score != 2 {
+		t.Errorf("tasks = %+v, want ids 7 and 8 with scores 5.5 and 2", got)
+	}
+
+	// Веса и горизонт передаются аргументами запроса, а не подставляются в текст
+	args := db.Calls()[0].Args
+	if args[0] != 1.0 || args[1] != 3.0 || args[3] != (7*24*time.Hour).Seconds() {
+		t.Errorf("score args = %v, want weights 1 and 3 and a horizon of one week", args[:4])
+	}
+}
+
+func TestTagTasksByFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		setup  func(db *dbtest.Mock)
+		status int
+		tagged int
+	}{
+		{
+			name:   "no filter",
+			target: "/tasks/tag-by-filter",
+			body:   `{"tag": "срочно"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "only include_deleted",
+			target: "/tasks/tag-by-filter?include_deleted=true",
+			body:   `{"tag": "срочно"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "empty tag",
+			target: "/tasks/tag-by-filter?status=pending",
+			body:   `{"tag": "  "}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "tagged",
+			target: "/tasks/tag-by-filter?status=pending",
+			body:   `{"tag": "Срочно"}`,
+			setup: func(db *dbtest.Mock) {
+				db.On("WITH tagged").Rows([]string{"id", "tags"},
+					[]driver.Value{int64(7), []byte("{дом}")}, []driver.Value{int64(8), []byte("{}")})
+				db.On("INSERT INTO tags").Affected(1)
+				db.On("INSERT INTO task_audit").Affected(1)
+			},
+			status: http.StatusOK,
+			tagged: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New()
+			defer db.Close()
+			if tt.setup != nil {
+				tt.setup(db)
+			}
+
+			w := httptest.NewRecorder()
+			newTestTaskHandler(db).TagTasksByFilter(w, newTestRequest("POST", tt.target, tt.body, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusBadRequest {
+				if len(db.Calls()) > 0 {
+					t.Errorf("rejected request reached the database: %v", db.Calls())
+				}
+				return
+			}
+
+			var got tagByFilterResult
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if got.Tagged != tt.tagged {
+				t.Errorf("tagged = %d, want %d", got.Tagged, tt.tagged)
+			}
+			// В журнал записываются метки до и после изменения
+			var audits []string
+			for _, call := range db.Calls() {
+				if strings.Contains(call.Query, "INSERT INTO task_audit") {
+					audits = append(audits, string(call.Args[3].([]byte)))
+				}
+			}
+			want := []string{`{"tags":{"old":["дом"],"new":["дом","срочно"]}}`, `{"tags":{"new":["срочно"]}}`}
+			if !slices.Equal(audits, want) {
+				t.Errorf("audit changes = %q, want %q", audits, want)
+			}
+		})
+	}
+}
+
+func TestDeleteTasks(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		setup   func(db *dbtest.Mock)
+		status  int
+		deleted int64
+	}{
+		{
+			name:   "no status filter",
+			target: "/tasks?confirm=true",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "not confirmed",
+			target: "/tasks?status=done",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "unknown status",
+			target: "/tasks?status=archived&confirm=true",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "done tasks deleted",
+			target: "/tasks?status=done&confirm=true",
+			setup: func(db *dbtest.Mock) {
+				db.On("WITH RECURSIVE subtree").Affected(3)
+			},
+			status:  http.StatusOK,
+			deleted: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New()
+			defer db.Close()
+			if tt.setup != nil {
+				tt.setup(db)
+			}
+
+			w := httptest.NewRecorder()
+			newTestTaskHandler(db).DeleteTasks(w, newTestRequest("DELETE", tt.target, "", nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusBadRequest {
+				if len(db.Calls()) > 0 {
+					t.Errorf("rejected request reached the database: %v", db.Calls())
+				}
+				return
+			}
+
+			var got deleteResult
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if got.Deleted != tt.deleted {
+				t.Errorf("deleted = %d, want %d", got.Deleted, tt.deleted)
+			}
+			if call := db.Calls()[0]; !strings.Contains(call.Query, "status = $2") || call.Args[1] != "done" {
+				t.Errorf("query = %s with args %v, want a status filter for done", call.Query, call.Args)
+			}
+		})
+	}
+}
+
+func TestDeleteTaskDeletableStatuses(t *testing.T) {
+	tests := []struct {
+		name      string
+		deletable []string
+		setup     func(db *dbtest.Mock)
+		status    int
+	}{
+		{
+			name: "any status without the setting",
+			setup: func(db *dbtest.Mock) {
+				db.On("WITH RECURSIVE subtree").Affected(1)
+			},
+			status: http.StatusNoContent,
+		},
+		{
+			name:      "allowed status",
+			deletable: []string{"pending", "done"},
+			setup: func(db *dbtest.Mock) {
+				db.On("WITH RECURSIVE subtree").Affected(1)
+			},
+			status: http.StatusNoContent,
+		},
+		{
+			name:      "task in progress",
+			deletable: []string{"pending", "done"},
+			setup: func(db *dbtest.Mock) {
+				db.On("WITH RECURSIVE subtree").Affected(0)
+				db.On("SELECT status FROM tasks").Rows([]string{"status"}, []driver.Value{"in_progress"})
+			},
+			status: http.StatusConflict,
+		},
+		{
+			name:      "missing task",
+			deletable: []string{"pending", "done"},
+			setup: func(db *dbtest.Mock) {
+				db.On("WITH RECURSIVE subtree").Affected(0)
+				db.On("SELECT status FROM tasks")
+			},
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New()
+			defer db.Close()
+			tt.setup(db)
+
+			h := newTestTaskHandler(db)
+			h.cfg.DeletableStatuses = tt.deletable
+			w := httptest.NewRecorder()
+			h.DeleteTask(w, newTestRequest("DELETE", "/tasks/7", "", map[string]string{"id": "7"}))
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body: %s", w.Code, tt.status, w.Body)
+			}
+			if restricted := strings.Contains(db.Calls()[0].Query, "status IN"); restricted != (len(tt.deletable) > 0) {
+				t.Errorf("delete query restricted by status = %v, want %v", restricted, len(tt.deletable) > 0)
+			}
+		})
+	}
+
+	t.Run("filtered delete of a protected status", func(t *testing.T) {
+		db := dbtest.New()
+		defer db.Close()
+
+		h := newTestTaskHandler(db)
+		h.cfg.DeletableStatuses = []string{"pending", "done"}
+		w := httptest.NewRecorder()
+		h.DeleteTasks(w, newTestRequest("DELETE", "/tasks?status=in_progress&confirm=true", "", nil))
+
+		if w.Code != http.StatusConflict || len(db.Calls()) > 0 {
+			t.Errorf("status = %d with %d queries, want %d without queries", w.Code, len(db.Calls()), http.StatusConflict)
+		}
+	})
+}
+
+func TestDeleteTaskSubtaskModes(t *testing.T) {
+	tests := []struct {
+		mode      string
+		orphan    bool
+		recursive bool
+	}{
+		{mode: "", recursive: true},
+		{mode: config.SubtaskDeleteCascade, recursive: true},
+		{mode: config.SubtaskDeleteOrphan, orphan: true},
+	}
+
+	for _, tt := range tests {
+		t.Run("mode "+tt.mode, func(t *testing.T) {
+			db := dbtest.New()
+			defer db.Close()
+			db.On("WITH orphaned").Affected(2)
+			db.On("WITH deleted").Affected(1)
+
+			h := newTestTaskHandler(db)
+			h.cfg.SubtaskDeleteMode = tt.mode
+			w := httptest.NewRecorder()
+			h.DeleteTask(w, newTestRequest("DELETE", "/tasks/7", "", map[string]string{"id": "7"}))
+
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("status = %d, want %d; body: %s", w.Code, http.StatusNoContent, w.Body)
+			}
+			calls := db.Calls()
+			orphaned := strings.Contains(calls[0].Query, "WITH orphaned")
+			if orphaned != tt.orphan {
+				t.Errorf("children orphaned = %v, want %v", orphaned, tt.orphan)
+			}
+			// Подзадачи отвязываются до удаления задачи, а удаление затрагивает
+			// поддерево только в каскадном режиме
+			deleteQuery := calls[len(calls)-1].Query
+			if !strings.Contains(deleteQuery, "WITH deleted") || strings.Contains(deleteQuery, "WITH RECURSIVE") != tt.recursive {
+				t.Errorf("delete query = %s, want recursive = %v", deleteQuery, tt.recursive)
+			}
+		})
+	}
+}
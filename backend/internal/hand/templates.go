@@ -0,0 +1,232 @@
+package hand
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/config"
+	"github.com/NickolaiP/taskApi/backend/internal/database"
+	"github.com/NickolaiP/taskApi/backend/internal/logger"
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// templateColumns перечисляет колонки таблицы task_templates в порядке, который ожидает scanTemplate.
+const templateColumns = "id, name, title, description, due_in_days, priority, tags, created_at"
+
+// scanTemplate считывает строку с колонками templateColumns в шаблон template.
+func scanTemplate(row rowScanner, template *models.TaskTemplate) error {
+	return row.Scan(&template.ID, &template.Name, &template.Title, &template.Description,
+		&template.DueInDays, &template.Priority, pq.Array(&template.Tags), &template.CreatedAt)
+}
+
+// templateHandler представляет собой структуру обработчика для шаблонов задач.
+// Включает в себя подключение к базе данных, логгер, настройки приложения
+// и таймаут операций с базой данных.
+type templateHandler struct {
+	db           database.Database
+	logger       *logger.Logger
+	cfg          config.AppConfig
+	queryTimeout time.Duration
+}
+
+// NewTemplateHandler создает новый экземпляр templateHandler с заданными базой данных,
+// логгером, настройками приложения и таймаутом операций с базой данных на один запрос.
+func NewTemplateHandler(db database.Database, logger *logger.Logger, cfg config.AppConfig, queryTimeout time.Duration) *templateHandler {
+	return &templateHandler{
+		db:           db,
+		logger:       logger,
+		cfg:          cfg,
+		queryTimeout: queryTimeout,
+	}
+}
+
+// log возвращает логгер запроса с контекстом ctx (с идентификатором запроса)
+// или общий логгер обработчика вне запроса.
+func (h *templateHandler) log(ctx context.Context) *logger.Logger {
+	return logger.FromContext(ctx, h.logger)
+}
+
+// logQueryError записывает в лог ошибку SQL-запроса с учетом настройки LOG_SQL_ARGS.
+func (h *templateHandler) logQueryError(ctx context.Context, err error, query string, args ...interface{}) {
+	logQueryError(h.log(ctx), h.cfg.LogSQLArgs, err, query, args...)
+}
+
+// CreateTemplate обрабатывает запрос на создание нового шаблона задачи.
+// Проверяет шаблон, сохраняет его в базе данных от имени текущего пользователя
+// и возвращает созданный шаблон в формате JSON.
+func (h *templateHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var template models.TaskTemplate
+	// Декодируем JSON-запрос в структуру template
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		// Возвращаем ошибку при некорректном запросе
+		writePayloadError(w, r, err)
+		return
+	}
+
+	// Проверяем обязательные поля шаблона
+	if err := template.Validate(); err != nil {
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Заполняем значения по умолчанию и время создания шаблона
+	if template.Priority == "" {
+		template.Priority = models.PriorityMedium
+	}
+	if template.Tags == nil {
+		template.Tags = []string{}
+	}
+	template.CreatedAt = time.Now().Format(time.RFC3339)
+
+	// Выполняем запрос на вставку нового шаблона в базу данных и получаем его ID
+	query := "INSERT INTO task_templates (name, title, description, due_in_days, priority, tags, created_at, user_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id"
+	args := []interface{}{template.Name, template.Title, template.Description, template.DueInDays, template.Priority, pq.Array(template.Tags), template.CreatedAt, currentUserID(r)}
+	if err := h.db.QueryRow(ctx, query, args...).Scan(&template.ID); err != nil {
+		// Логируем запрос и возвращаем ошибку сервера, если вставка не удалась
+		h.logQueryError(ctx, err, query, args...)
+		writeServerError(ctx, w, r, err, "Error creating template")
+		return
+	}
+
+	// Устанавливаем статус ответа как Created и возвращаем созданный шаблон
+	writeResponse(h.log(r.Context()), w, r, http.StatusCreated, template)
+}
+
+// GetTemplates обрабатывает запрос на получение списка шаблонов задач текущего пользователя.
+func (h *templateHandler) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Выполняем запрос на выборку шаблонов пользователя из базы данных
+	userID := currentUserID(r)
+	query := "SELECT " + templateColumns + " FROM task_templates WHERE user_id=$1 ORDER BY id"
+	rows, err := h.db.Query(ctx, query, userID)
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, userID)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	defer rows.Close()
+
+	templates := []models.TaskTemplate{}
+	// Итерируем по результатам выборки и заполняем срез шаблонов
+	for rows.Next() {
+		var template models.TaskTemplate
+		if err := scanTemplate(rows, &template); err != nil {
+			// Возвращаем ошибку сервера при сбое сканирования
+			writeServerError(ctx, w, r, err, "Server error")
+			return
+		}
+		templates = append(templates, template)
+	}
+
+	// Возвращаем шаблоны в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, templates)
+}
+
+// CreateTaskFromTemplate обрабатывает запрос на создание задачи из шаблона.
+// Копирует заголовок, описание, приоритет и метки шаблона, вычисляет срок выполнения
+// по смещению шаблона и возвращает созданную задачу в формате JSON с адресом в заголовке Location. Шаблоны других пользователей
+// не видны: для них, как и для несуществующих, возвращается 404.
+func (h *templateHandler) CreateTaskFromTemplate(w http.ResponseWriter, r *http.Request) {
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Извлекаем ID шаблона из параметров запроса
+	vars := mux.Vars(r)
+	templateID, err := strconv.Atoi(vars["templateId"])
+	if err != nil {
+		// Возвращаем ошибку при некорректном ID
+		writeError(w, r, "Invalid template ID", http.StatusBadRequest)
+		return
+	}
+
+	// Проверяем, что шаблон существует и принадлежит пользователю, и получаем его поля
+	var template models.TaskTemplate
+	userID := currentUserID(r)
+	query := "SELECT " + templateColumns + " FROM task_templates WHERE id=$1 AND user_id=$2"
+	err = scanTemplate(h.db.QueryRow(ctx, query, templateID, userID), &template)
+	if err == sql.ErrNoRows {
+		// Возвращаем ошибку, если шаблон не найден
+		writeError(w, r, "Template not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, templateID, userID)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+
+	// Заполняем задачу из шаблона
+	now := time.Now()
+	task := models.Task{
+		Title:       template.Title,
+		Description: template.Description,
+		Status:      models.StatusPending,
+		Priority:    template.Priority,
+		Tags:        template.Tags,
+		CreatedAt:   now.Format(time.RFC3339),
+		UserID:      userID,
+	}
+	task.UpdatedAt = task.CreatedAt
+	if template.DueInDays != nil {
+		task.DueDate = now.AddDate(0, 0, *template.DueInDays).Format(time.RFC3339)
+	}
+
+	// Вставляем новую задачу, ее метки и запись о ее создании в журнал изменений в одной транзакции
+	tx, err := h.db.BeginTx(ctx)
+	if err != nil {
+		h.log(ctx).Error("Failed to begin transaction", "error", err)
+		writeServerError(ctx, w, r, err, "Error creating task")
+		return
+	}
+	// После успешного Commit откат ничего не делает
+	defer tx.Rollback()
+
+	// Выполняем запрос на вставку новой задачи в базу данных и получаем её ID, позицию и версию
+	query = "INSERT INTO tasks (title, description, status, priority, due_date, created_at, updated_at, user_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, position, version"
+	args := []interface{}{task.Title, task.Description, task.Status, task.Priority, dueDateArg(task.DueDate), task.CreatedAt, task.UpdatedAt, task.UserID}
+	if err := tx.QueryRow(ctx, query, args...).Scan(&task.ID, &task.Position, &task.Version); err != nil {
+		// Логируем запрос и возвращаем ошибку сервера, если вставка не удалась
+		h.logQueryError(ctx, err, query, args...)
+		writeServerError(ctx, w, r, err, "Error creating task")
+		return
+	}
+	if err := saveTaskTags(ctx, tx, h.logQueryError, task.UserID, task.ID, task.Tags); err != nil {
+		writeServerError(ctx, w, r, err, "Error creating task")
+		return
+	}
+	args = auditArgs(task.UserID, task.ID, models.AuditCreated, auditDiff(nil, task))
+	if _, err := tx.Exec(ctx, insertAuditQuery, args...); err != nil {
+		h.logQueryError(ctx, err, insertAuditQuery, args...)
+		writeServerError(ctx, w, r, err, "Error creating task")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		h.log(ctx).Error("Failed to commit transaction", "error", err)
+		writeServerError(ctx, w, r, err, "Error creating task")
+		return
+	}
+
+	// Указываем адрес и версию созданной задачи
+	w.Header().Set("Location", taskLocation(task.ID))
+	w.Header().Set("ETag", taskETag(task.Version))
+
+	// Устанавливаем статус ответа как Created и возвращаем созданную задачу
+	writeResponse(h.log(r.Context()), w, r, http.StatusCreated, task)
+}
@@ -0,0 +1,200 @@
+package hand
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/auth"
+	"github.com/NickolaiP/taskApi/backend/internal/config"
+	"github.com/NickolaiP/taskApi/backend/internal/database"
+	"github.com/NickolaiP/taskApi/backend/internal/logger"
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// userHandler представляет собой структуру обработчика для учетных записей пользователей.
+// Включает в себя подключение к базе данных, логгер, настройки приложения,
+// таймаут операций с базой данных и настройки аутентификации.
+type userHandler struct {
+	db           database.Database
+	logger       *logger.Logger
+	cfg          config.AppConfig
+	queryTimeout time.Duration
+	authCfg      config.AuthConfig
+}
+
+// NewUserHandler создает новый экземпляр userHandler с заданными базой данных,
+// логгером, настройками приложения, таймаутом операций с базой данных на один запрос
+// и настройками аутентификации.
+func NewUserHandler(db database.Database, logger *logger.Logger, cfg config.AppConfig, queryTimeout time.Duration, authCfg config.AuthConfig) *userHandler {
+	return &userHandler{
+		db:           db,
+		logger:       logger,
+		cfg:          cfg,
+		queryTimeout: queryTimeout,
+		authCfg:      authCfg,
+	}
+}
+
+// loginResponse - ответ на успешный вход: токен доступа и момент истечения его срока.
+type loginResponse struct {
+	Token     string `json:"token" xml:"token"`
+	TokenType string `json:"token_type" xml:"token_type"`
+	ExpiresAt string `json:"expires_at" xml:"expires_at"`
+}
+
+// log возвращает логгер запроса с контекстом ctx (с идентификатором запроса)
+// или общий логгер обработчика вне запроса.
+func (h *userHandler) log(ctx context.Context) *logger.Logger {
+	return logger.FromContext(ctx, h.logger)
+}
+
+// logQueryError записывает в лог ошибку SQL-запроса с учетом настройки LOG_SQL_ARGS.
+func (h *userHandler) logQueryError(ctx context.Context, err error, query string, args ...interface{}) {
+	logQueryError(h.log(ctx), h.cfg.LogSQLArgs, err, query, args...)
+}
+
+// Register обрабатывает запрос на регистрацию пользователя.
+// Принимает {"email": "...", "password": "..."}, сохраняет хеш пароля и возвращает
+// созданного пользователя в формате JSON. Занятый адрес отклоняется ответом 409.
+// Пользователь с адресом из LEGACY_OWNER_EMAIL получает задачи и шаблоны без владельца.
+func (h *userHandler) Register(w http.ResponseWriter, r *http.Request) {
+	var creds models.Credentials
+	// Декодируем JSON-запрос в структуру creds
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		// Возвращаем ошибку при некорректном запросе
+		writePayloadError(w, r, err)
+		return
+	}
+
+	// Проверяем адрес и пароль
+	if err := creds.Validate(); err != nil {
+		writeError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Сохраняем только хеш пароля
+	hash, err := auth.HashPassword(creds.Password)
+	if err != nil {
+		h.log(r.Context()).Error("Failed to hash password", "error", err)
+		writeError(w, r, "Error creating user", http.StatusInternalServerError)
+		return
+	}
+
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	user := models.User{
+		Email:        creds.Email,
+		PasswordHash: hash,
+		CreatedAt:    time.Now().Format(time.RFC3339),
+	}
+
+	// Выполняем запрос на вставку пользователя и получаем его ID;
+	// пароль не передается в лог даже при включенном LOG_SQL_ARGS
+	query := "INSERT INTO users (email, password_hash, created_at) VALUES ($1, $2, $3) RETURNING id"
+	err = h.db.QueryRow(ctx, query, user.Email, user.PasswordHash, user.CreatedAt).Scan(&user.ID)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		// Возвращаем ошибку, если адрес уже зарегистрирован
+		writeError(w, r, "User with this email already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера, если вставка не удалась
+		h.logQueryError(ctx, err, query, user.Email)
+		writeServerError(ctx, w, r, err, "Error creating user")
+		return
+	}
+
+	// Владелец данных, созданных до появления учетных записей, получает их сразу
+	// при регистрации; при сбое они будут переданы при следующем запуске сервиса
+	if h.authCfg.LegacyOwnerEmail != "" && user.Email == h.authCfg.LegacyOwnerEmail {
+		tasks, templates, err := database.ClaimLegacyData(ctx, h.db, user.Email)
+		if err != nil {
+			h.log(ctx).Error("Failed to claim legacy data", "error", err)
+		} else {
+			h.log(ctx).Info("Claimed legacy data", "tasks", tasks, "templates", templates)
+		}
+	}
+
+	// Устанавливаем статус ответа как Created и возвращаем созданного пользователя
+	writeResponse(h.log(r.Context()), w, r, http.StatusCreated, user)
+}
+
+// Login обрабатывает запрос на вход пользователя.
+// Принимает {"email": "...", "password": "..."} и при верных учетных данных возвращает
+// подписанный токен доступа, который передается в заголовке "Authorization: Bearer".
+// Неизвестный адрес и неверный пароль одинаково отклоняются ответом 401.
+func (h *userHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var creds models.Credentials
+	// Декодируем JSON-запрос в структуру creds
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		// Возвращаем ошибку при некорректном запросе
+		writePayloadError(w, r, err)
+		return
+	}
+
+	// Создаем контекст с таймаутом для операции с базой данных
+	ctx, cancel := context.WithTimeout(r.Context(), h.queryTimeout)
+	defer cancel()
+
+	// Получаем пользователя по адресу; адреса хранятся в нижнем регистре
+	var user models.User
+	email := strings.ToLower(strings.TrimSpace(creds.Email))
+	query := "SELECT id, password_hash FROM users WHERE email=$1"
+	err := h.db.QueryRow(ctx, query, email).Scan(&user.ID, &user.PasswordHash)
+	if err == sql.ErrNoRows {
+		writeError(w, r, "Invalid email or password", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		// Логируем запрос и возвращаем ошибку сервера при сбое запроса
+		h.logQueryError(ctx, err, query, email)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+
+	// Проверяем пароль
+	valid, err := auth.CheckPassword(user.PasswordHash, creds.Password)
+	if err != nil {
+		h.log(ctx).Error("Failed to check password", "error", err, "user_id", user.ID)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+	if !valid {
+		writeError(w, r, "Invalid email or password", http.StatusUnauthorized)
+		return
+	}
+
+	// Выпускаем токен доступа
+	now := time.Now()
+	token, err := auth.IssueToken([]byte(h.authCfg.JWTSecret), user.ID, h.authCfg.TokenTTL, now)
+	if err != nil {
+		h.log(ctx).Error("Failed to issue token", "error", err, "user_id", user.ID)
+		writeServerError(ctx, w, r, err, "Server error")
+		return
+	}
+
+	// Возвращаем токен в формате JSON
+	writeResponse(h.log(r.Context()), w, r, http.StatusOK, loginResponse{
+		Token:     token,
+		TokenType: "Bearer",
+		ExpiresAt: now.Add(h.authCfg.TokenTTL).UTC().Format(time.RFC3339),
+	})
+}
+
+// currentUserID возвращает ID аутентифицированного пользователя запроса.
+// Маршруты задач доступны только через middleware аутентификации, поэтому
+// ID всегда присутствует; нулевой ID не совпадает ни с одной задачей.
+func currentUserID(r *http.Request) int {
+	userID, _ := auth.UserID(r.Context())
+	return userID
+}
@@ -0,0 +1,23 @@
+package logger
+
+import "context"
+
+// contextKey - тип ключей контекста пакета, не пересекающийся с ключами других пакетов.
+type contextKey int
+
+// loggerKey - ключ контекста запроса, под которым хранится логгер запроса.
+const loggerKey contextKey = iota
+
+// WithLogger возвращает копию контекста с логгером l, например с атрибутами
+// текущего запроса.
+func WithLogger(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// FromContext возвращает логгер из контекста или fallback, если в контексте его нет.
+func FromContext(ctx context.Context, fallback *Logger) *Logger {
+	if l, ok := ctx.Value(loggerKey).(*Logger); ok {
+		return l
+	}
+	return fallback
+}
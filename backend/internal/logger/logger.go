@@ -2,6 +2,7 @@ package logger
 
 import (
 	"io"
+	"strings"
 
 	"golang.org/x/exp/slog"
 )
@@ -12,24 +13,61 @@ type Logger struct {
 	*slog.Logger
 }
 
+// parseLevel преобразует название уровня логирования (debug, info, warn, error)
+// в уровень slog. Второе значение равно false, если название неизвестно.
+// Пустое название означает уровень Info.
+func parseLevel(level string) (slog.Level, bool) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, true
+	case "", "info":
+		return slog.LevelInfo, true
+	case "warn", "warning":
+		return slog.LevelWarn, true
+	case "error":
+		return slog.LevelError, true
+	default:
+		return slog.LevelInfo, false
+	}
+}
+
 // InitLogger инициализирует новый экземпляр Logger с указанным выходным потоком.
-// Эта функция настраивает логгер для записи логов в формате JSON с уровнем логирования Info.
+// Эта функция настраивает логгер с заданными уровнем и форматом логирования.
 // Аргументы:
 //
 //	w - io.Writer, который будет использоваться для записи логов (например, файл, stdout).
+//	level - уровень логирования: debug, info, warn или error. Неизвестное значение
+//	заменяется на info с предупреждением в логе.
+//	format - формат записей: text для удобного чтения человеком, иначе JSON.
 //
 // Возвращает:
 //
-//	*Logger - новый экземпляр Logger, настроенный для записи логов в формате JSON.
-func InitLogger(w io.Writer) *Logger {
-	// Создаем опции для обработчика логов, устанавливая уровень логирования на Info.
+//	*Logger - новый экземпляр Logger, настроенный для записи логов.
+func InitLogger(w io.Writer, level, format string) *Logger {
+	// Создаем опции для обработчика логов с указанным уровнем логирования.
+	slogLevel, known := parseLevel(level)
 	options := &slog.HandlerOptions{
-		Level: slog.LevelInfo,
+		Level: slogLevel,
 	}
 
-	// Создаем новый JSON-обработчик для записи логов в указанный выходной поток.
-	handler := slog.NewJSONHandler(w, options)
+	// Создаем обработчик для записи логов в указанный выходной поток:
+	// текстовый для локальной разработки или JSON по умолчанию.
+	var handler slog.Handler
+	if strings.EqualFold(format, "text") {
+		handler = slog.NewTextHandler(w, options)
+	} else {
+		handler = slog.NewJSONHandler(w, options)
+	}
+
+	// Создаем новый экземпляр Logger, использующий созданный обработчик.
+	logger := &Logger{Logger: slog.New(handler)}
+	if !known {
+		logger.Warn("Unknown log level, falling back to info", "level", level)
+	}
+	return logger
+}
 
-	// Возвращаем новый экземпляр Logger, использующий созданный обработчик.
-	return &Logger{Logger: slog.New(handler)}
+// With возвращает Logger, добавляющий атрибуты args к каждой записи.
+func (l *Logger) With(args ...interface{}) *Logger {
+	return &Logger{Logger: l.Logger.With(args...)}
 }
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry накапливает метрики HTTP-запросов и по запросу отдает их вместе
+// с текущей статистикой пула соединений и метриками процесса в формате Prometheus.
+// Метрики хранятся в собственном реестре, а не в глобальном реестре библиотеки.
+// Безопасен для одновременного использования.
+type Registry struct {
+	requests  *prometheus.CounterVec
+	durations *prometheus.HistogramVec
+	handler   http.Handler
+}
+
+// NewRegistry создает Registry, который берет статистику пула соединений из dbStats.
+func NewRegistry(dbStats func() sql.DBStats) *Registry {
+	m := &Registry{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Total number of HTTP requests by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request duration in seconds by route and method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		m.requests,
+		m.durations,
+		newDBStatsCollector(dbStats),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	m.handler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	return m
+}
+
+// ObserveRequest учитывает обработанный запрос: маршрут route (шаблон пути, а не сам путь,
+// чтобы количество рядов метрик не росло с количеством задач), метод, код ответа и длительность.
+func (m *Registry) ObserveRequest(route, method string, status int, duration time.Duration) {
+	m.requests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	m.durations.WithLabelValues(route, method).Observe(duration.Seconds())
+}
+
+// ServeHTTP отдает текущие значения метрик в формате Prometheus.
+func (m *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.handler.ServeHTTP(w, r)
+}
+
+// dbStatsCollector публикует статистику пула соединений, прочитанную в момент запроса метрик.
+type dbStatsCollector struct {
+	dbStats func() sql.DBStats
+
+	maxOpen      *prometheus.Desc
+	open         *prometheus.Desc
+	inUse        *prometheus.Desc
+	idle         *prometheus.Desc
+	waitCount    *prometheus.Desc
+	waitDuration *prometheus.Desc
+}
+
+// newDBStatsCollector создает сборщик статистики пула соединений из dbStats.
+func newDBStatsCollector(dbStats func() sql.DBStats) *dbStatsCollector {
+	return &dbStatsCollector{
+		dbStats:      dbStats,
+		maxOpen:      prometheus.NewDesc("db_max_open_connections", "Maximum number of open connections to the database.", nil, nil),
+		open:         prometheus.NewDesc("db_open_connections", "Number of established connections, both in use and idle.", nil, nil),
+		inUse:        prometheus.NewDesc("db_in_use_connections", "Number of connections currently in use.", nil, nil),
+		idle:         prometheus.NewDesc("db_idle_connections", "Number of idle connections.", nil, nil),
+		waitCount:    prometheus.NewDesc("db_wait_count_total", "Total number of connections waited for.", nil, nil),
+		waitDuration: prometheus.NewDesc("db_wait_duration_seconds_total", "Total time blocked waiting for a new connection.", nil, nil),
+	}
+}
+
+// Describe реализует prometheus.Collector.
+func (c *dbStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.maxOpen
+	ch <- c.open
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+}
+
+// Collect реализует prometheus.Collector.
+func (c *dbStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.dbStats()
+	ch <- prometheus.MustNewConstMetric(c.maxOpen, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/auth"
+	"github.com/NickolaiP/taskApi/backend/internal/database"
+	"github.com/NickolaiP/taskApi/backend/internal/logger"
+	"github.com/NickolaiP/taskApi/backend/internal/render"
+)
+
+// RequireAdmin возвращает middleware, которое пропускает только запросы пользователей
+// с адресами из emails (в нижнем регистре); остальные запросы отклоняются ответом 403.
+// Должно выполняться после Authenticate: пользователь берется из контекста запроса,
+// а его адрес читается из базы данных db с таймаутом queryTimeout, поэтому изменение
+// списка вступает в силу без повторного входа.
+func RequireAdmin(l *logger.Logger, db database.Database, queryTimeout time.Duration, emails []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := auth.UserID(r.Context())
+			if !ok {
+				unauthorized(w, r)
+				return
+			}
+
+			// Создаем контекст с таймаутом для чтения адреса пользователя
+			ctx, cancel := context.WithTimeout(r.Context(), queryTimeout)
+			var email string
+			err := db.QueryRow(ctx, "SELECT email FROM users WHERE id=$1", userID).Scan(&email)
+			cancel()
+			if err != nil && err != sql.ErrNoRows {
+				logger.FromContext(r.Context(), l).Error("Failed to check admin access", "error", err)
+				render.Error(w, r, "Server error", http.StatusInternalServerError)
+				return
+			}
+			if err == sql.ErrNoRows || !slices.Contains(emails, email) {
+				render.Error(w, r, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
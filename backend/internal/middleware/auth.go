@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/auth"
+	"github.com/NickolaiP/taskApi/backend/internal/database"
+	"github.com/NickolaiP/taskApi/backend/internal/logger"
+	"github.com/NickolaiP/taskApi/backend/internal/render"
+)
+
+const (
+	// bearerPrefix - префикс значения заголовка Authorization с токеном доступа.
+	bearerPrefix = "Bearer "
+	// APIKeyHeader - заголовок запроса с API-ключом.
+	APIKeyHeader = "X-API-Key"
+)
+
+var (
+	// errNoCredentials означает, что запрос не содержит учетных данных
+	// проверяемого вида, и нужно попробовать следующий способ аутентификации.
+	errNoCredentials = errors.New("no credentials")
+	// errInvalidCredentials означает, что учетные данные переданы, но недействительны.
+	errInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Authenticator определяет пользователя по учетным данным запроса одного вида.
+// Возвращает errNoCredentials, если таких данных в запросе нет, errInvalidCredentials,
+// если они недействительны, и любую другую ошибку при сбое проверки.
+type Authenticator func(r *http.Request) (int, error)
+
+// Authenticate возвращает middleware, которое проверяет запрос способами authenticators
+// по порядку и сохраняет ID пользователя в контексте запроса. Решение принимает первый
+// способ, нашедший в запросе свои учетные данные. Запросы без учетных данных или
+// с недействительными данными отклоняются ответом 401. Каждая проверка ограничена
+// таймаутом queryTimeout, как и запросы к базе данных в обработчиках.
+func Authenticate(l *logger.Logger, queryTimeout time.Duration, authenticators ...Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, authenticate := range authenticators {
+				// Создаем контекст с таймаутом для проверки, которая может обращаться к базе данных
+				ctx, cancel := context.WithTimeout(r.Context(), queryTimeout)
+				userID, err := authenticate(r.WithContext(ctx))
+				cancel()
+				if errors.Is(err, errNoCredentials) {
+					continue
+				}
+				if errors.Is(err, errInvalidCredentials) {
+					unauthorized(w, r)
+					return
+				}
+				if err != nil {
+					logger.FromContext(r.Context(), l).Error("Failed to authenticate request", "error", err)
+					render.Error(w, r, "Server error", http.StatusInternalServerError)
+					return
+				}
+				next.ServeHTTP(w, r.WithContext(auth.WithUserID(r.Context(), userID)))
+				return
+			}
+			unauthorized(w, r)
+		})
+	}
+}
+
+// BearerToken возвращает способ аутентификации по токену доступа из заголовка
+// "Authorization: Bearer <token>", подписанному секретом secret.
+func BearerToken(secret []byte) Authenticator {
+	return func(r *http.Request) (int, error) {
+		header := r.Header.Get("Authorization")
+		if len(header) < len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+			return 0, errNoCredentials
+		}
+		userID, err := auth.ParseToken(secret, strings.TrimSpace(header[len(bearerPrefix):]), time.Now())
+		if err != nil {
+			return 0, errInvalidCredentials
+		}
+		return userID, nil
+	}
+}
+
+// APIKey возвращает способ аутентификации по API-ключу из заголовка X-API-Key.
+// Ключ ищется по хешу среди неотозванных ключей в базе данных db.
+func APIKey(db database.Database) Authenticator {
+	return func(r *http.Request) (int, error) {
+		return lookupAPIKey(r, db, r.Header.Get(APIKeyHeader))
+	}
+}
+
+// CalendarTokenParam возвращает способ аутентификации по токену календаря из параметра
+// запроса name. Приложения календаря не умеют передавать заголовки, поэтому токен
+// передается в адресе и попадает в историю браузера и журналы прокси-серверов:
+// способ регистрируется только для маршрута календаря, а токен ищется по хешу
+// среди неотозванных токенов в базе данных db и не принимается как API-ключ.
+func CalendarTokenParam(db database.Database, name string) Authenticator {
+	return func(r *http.Request) (int, error) {
+		token := r.URL.Query().Get(name)
+		if token == "" {
+			return 0, errNoCredentials
+		}
+
+		var userID int
+		query := "SELECT user_id FROM calendar_tokens WHERE token_hash=$1 AND revoked_at IS NULL"
+		err := db.QueryRow(r.Context(), query, auth.HashAPIKey(token)).Scan(&userID)
+		if err == sql.ErrNoRows {
+			return 0, errInvalidCredentials
+		}
+		if err != nil {
+			return 0, err
+		}
+		return userID, nil
+	}
+}
+
+// lookupAPIKey определяет владельца API-ключа key, переданного в запросе r.
+// Ключ ищется по хешу среди неотозванных ключей в базе данных db; время поиска
+// ограничено контекстом запроса, который задает Authenticate.
+func lookupAPIKey(r *http.Request, db database.Database, key string) (int, error) {
+	if key == "" {
+		return 0, errNoCredentials
+	}
+
+	var userID int
+	query := "SELECT user_id FROM api_keys WHERE key_hash=$1 AND revoked_at IS NULL"
+	err := db.QueryRow(r.Context(), query, auth.HashAPIKey(key)).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, errInvalidCredentials
+	}
+	if err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
+
+// unauthorized отправляет ответ 401 с приглашением к аутентификации по токену.
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	render.Error(w, r, "Unauthorized", http.StatusUnauthorized)
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/NickolaiP/taskApi/backend/internal/render"
+)
+
+// LimitBody возвращает middleware, которое ограничивает размер тела запроса limit байтами.
+// Запрос с заявленным в Content-Length большим телом сразу отклоняется ответом 413;
+// чтение тела без Content-Length (например, chunked) прерывается на превышении
+// ограничения ошибкой *http.MaxBytesError, на которую обработчики отвечают 413.
+// Неположительный limit отключает ограничение.
+func LimitBody(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				render.Error(w, r, fmt.Sprintf("Request body too large: limit is %d bytes", limit), http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
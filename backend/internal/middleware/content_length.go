@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/NickolaiP/taskApi/backend/internal/render"
+)
+
+// RequireContentLength возвращает middleware, которое отклоняет запросы на запись
+// (POST, PUT, PATCH) без заголовка Content-Length, в том числе с chunked-кодированием,
+// ответом 411 Length Required. Нужно для прокси, которые плохо поддерживают chunked-тела.
+func RequireContentLength(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			// ContentLength равен -1, если длина тела неизвестна
+			if r.ContentLength < 0 || len(r.TransferEncoding) > 0 {
+				render.Error(w, r, "Content-Length required", http.StatusLengthRequired)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
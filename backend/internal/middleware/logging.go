@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// RequestIDHeader - заголовок с идентификатором запроса, по которому можно найти
+// записи о запросе в логе. Клиент или прокси могут передать свой идентификатор
+// в запросе, иначе он генерируется; в ответе идентификатор возвращается всегда.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength - максимальная длина идентификатора запроса, принимаемого от клиента.
+const maxRequestIDLength = 128
+
+// statusRecorder оборачивает http.ResponseWriter и запоминает код ответа
+// и количество записанных байт тела.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	// Запись тела без явного WriteHeader означает ответ 200
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// unmatchedRoute - маршрут в метриках запросов, не совпавших ни с одним маршрутом.
+const unmatchedRoute = "unmatched"
+
+// RequestObserver получает сведения о каждом обработанном запросе, например для сбора метрик.
+type RequestObserver interface {
+	// ObserveRequest вызывается после обработки запроса с шаблоном пути совпавшего маршрута,
+	// методом, кодом ответа и длительностью обработки.
+	ObserveRequest(route, method string, status int, duration time.Duration)
+}
+
+// matchedRouteKey - ключ контекста запроса, по которому RecordRoute
+// передает шаблон пути маршрута в RequestLogger.
+type matchedRouteKey struct{}
+
+// matchedRoute хранит шаблон пути маршрута, совпавшего с запросом.
+type matchedRoute struct {
+	template string
+}
+
+// RecordRoute - middleware маршрутизатора, которое сообщает RequestLogger шаблон пути
+// совпавшего маршрута (например, /tasks/{id}). RequestLogger оборачивает маршрутизатор
+// снаружи и сам маршрут не видит.
+func RecordRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if matched, ok := r.Context().Value(matchedRouteKey{}).(*matchedRoute); ok {
+			if route := mux.CurrentRoute(r); route != nil {
+				if template, err := route.GetPathTemplate(); err == nil {
+					matched.template = template
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newRequestID генерирует случайный идентификатор запроса из 16 байт в шестнадцатеричном виде.
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// validRequestID сообщает, можно ли использовать идентификатор запроса id, полученный
+// от клиента: допускаются только буквы, цифры и символы "-", "_", ".", чтобы
+// идентификатор нельзя было использовать для подделки записей лога.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// RequestLogger возвращает middleware, которое записывает в лог каждый запрос:
+// метод, путь, код ответа, размер тела ответа, длительность и идентификатор запроса.
+// Идентификатор берется из заголовка X-Request-ID запроса или генерируется и возвращается
+// клиенту в заголовке X-Request-ID ответа. В контекст запроса сохраняется логгер
+// с этим идентификатором (см. logger.FromContext), чтобы все записи обработки
+// запроса можно было связать между собой. Сведения о запросе передаются и каждому
+// из observers; маршрут для них определяет RecordRoute.
+func RequestLogger(l *logger.Logger, observers ...RequestObserver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			requestID := r.Header.Get(RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+			requestLogger := l.With("request_id", requestID)
+
+			rec := &statusRecorder{ResponseWriter: w}
+			matched := &matchedRoute{}
+			ctx := context.WithValue(r.Context(), matchedRouteKey{}, matched)
+			next.ServeHTTP(rec, r.WithContext(logger.WithLogger(ctx, requestLogger)))
+
+			duration := time.Since(start)
+
+			// Обработчик, не записавший ничего, отвечает 200
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			requestLogger.Info("HTTP request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", status,
+				"bytes", rec.bytes,
+				"duration_ms", float64(duration.Microseconds())/1000,
+			)
+
+			route := matched.template
+			if route == "" {
+				route = unmatchedRoute
+			}
+			for _, observer := range observers {
+				observer.ObserveRequest(route, r.Method, status, duration)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/NickolaiP/taskApi/backend/internal/render"
+)
+
+// RateLimiter ограничивает частоту запросов с одного IP-адреса по алгоритму
+// корзины токенов (rate.Limiter на каждого клиента): корзина вмещает burst токенов
+// и пополняется со скоростью perMinute токенов в минуту, каждый запрос расходует один токен.
+type RateLimiter struct {
+	limit rate.Limit
+	burst int
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+	stop     chan struct{}
+}
+
+// NewRateLimiter создает ограничитель на perMinute запросов в минуту с одного IP-адреса
+// с допустимым всплеском до burst запросов и запускает периодическую очистку
+// ограничителей неактивных клиентов с интервалом cleanupInterval. Очистку останавливает Stop.
+func NewRateLimiter(perMinute, burst int, cleanupInterval time.Duration) *RateLimiter {
+	l := &RateLimiter{
+		limit:    rate.Limit(float64(perMinute) / 60),
+		burst:    burst,
+		limiters: make(map[string]*rate.Limiter),
+		stop:     make(chan struct{}),
+	}
+	go l.cleanupLoop(cleanupInterval)
+	return l
+}
+
+// allow расходует токен клиента key на момент now. Если токенов нет, возвращает false
+// и время, через которое появится следующий токен.
+func (l *RateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	limiter, ok := l.limiters[key]
+	if !ok {
+		limiter = rate.NewLimiter(l.limit, l.burst)
+		l.limiters[key] = limiter
+	}
+	l.mu.Unlock()
+
+	// Резервируем токен; если его придется ждать, отменяем резерв и сообщаем время ожидания
+	reservation := limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// cleanup удаляет ограничители, которые к моменту now полностью пополнились:
+// такие клиенты неотличимы от новых, поэтому их состояние хранить не нужно.
+func (l *RateLimiter) cleanup(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, limiter := range l.limiters {
+		if limiter.TokensAt(now) >= float64(l.burst) {
+			delete(l.limiters, key)
+		}
+	}
+}
+
+// cleanupLoop периодически очищает корзины до вызова Stop.
+func (l *RateLimiter) cleanupLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			l.cleanup(now)
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// Stop останавливает периодическую очистку корзин.
+func (l *RateLimiter) Stop() {
+	close(l.stop)
+}
+
+// Middleware возвращает middleware, которое отклоняет запросы сверх лимита ответом
+// 429 Too Many Requests с заголовком Retry-After (в секундах). Клиент определяется
+// по IP-адресу соединения; заголовки прокси вроде X-Forwarded-For не учитываются,
+// так как их может подделать сам клиент.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+
+		allowed, retryAfter := l.allow(ip, time.Now())
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			render.Error(w, r, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
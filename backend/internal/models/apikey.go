@@ -0,0 +1,12 @@
+package models
+
+// APIKey описывает API-ключ пользователя. Сам ключ возвращается клиенту
+// только один раз при создании; в базе данных хранится лишь его хеш.
+type APIKey struct {
+	ID        int    `json:"id" xml:"id"`
+	Name      string `json:"name" xml:"name"`
+	Prefix    string `json:"prefix" xml:"prefix"`
+	Key       string `json:"key,omitempty" xml:"key,omitempty"`
+	CreatedAt string `json:"created_at" xml:"created_at"`
+	RevokedAt string `json:"revoked_at,omitempty" xml:"revoked_at,omitempty"`
+}
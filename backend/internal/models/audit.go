@@ -0,0 +1,25 @@
+package models
+
+import "encoding/json"
+
+// Действия, записываемые в журнал изменений задачи.
+const (
+	AuditCreated  = "created"
+	AuditUpdated  = "updated"
+	AuditDeleted  = "deleted"
+	AuditRestored = "restored"
+)
+
+// TaskAuditEntry описывает запись журнала изменений задачи: кто, когда и как
+// изменил задачу. Changes содержит изменившиеся поля с прежними (old) и новыми (new)
+// значениями; у созданной задачи есть только новые значения, а у удаления
+// и восстановления поле отсутствует. UserID отсутствует у изменений, сделанных
+// самим сервисом (например, создания следующего повторения задачи).
+type TaskAuditEntry struct {
+	ID        int             `json:"id" xml:"id"`
+	TaskID    int             `json:"task_id" xml:"task_id"`
+	UserID    int             `json:"user_id,omitempty" xml:"user_id,omitempty"`
+	Action    string          `json:"action" xml:"action"`
+	Changes   json.RawMessage `json:"changes,omitempty" xml:"changes,omitempty"`
+	CreatedAt string          `json:"created_at" xml:"created_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// CalendarToken описывает токен подписки на календарь задач пользователя. Токен
+// дает доступ только к чтению календаря; сам токен возвращается клиенту только
+// один раз при создании, в базе данных хранится лишь его хеш.
+type CalendarToken struct {
+	ID        int    `json:"id" xml:"id"`
+	Name      string `json:"name" xml:"name"`
+	Prefix    string `json:"prefix" xml:"prefix"`
+	Token     string `json:"token,omitempty" xml:"token,omitempty"`
+	CreatedAt string `json:"created_at" xml:"created_at"`
+	RevokedAt string `json:"revoked_at,omitempty" xml:"revoked_at,omitempty"`
+}
@@ -1,10 +1,329 @@
 package models
 
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxTitleLength - максимальная длина заголовка задачи в символах,
+// совпадающая с размером колонки title VARCHAR(255).
+const MaxTitleLength = 255
+
+// MaxTagLength - максимальная длина метки задачи в символах,
+// совпадающая с размером колонки tags.name VARCHAR(50).
+const MaxTagLength = 50
+
+// MaxBlockedReasonLength - максимальная длина причины блокировки задачи в символах.
+const MaxBlockedReasonLength = 1000
+
+// Возможные статусы задачи.
+const (
+	StatusPending    = "pending"
+	StatusInProgress = "in_progress"
+	StatusDone       = "done"
+)
+
+// Statuses перечисляет все допустимые статусы задачи.
+var Statuses = []string{StatusPending, StatusInProgress, StatusDone}
+
+// Возможные приоритеты задачи.
+const (
+	PriorityLow    = "low"
+	PriorityMedium = "medium"
+	PriorityHigh   = "high"
+)
+
+// Priorities перечисляет все допустимые приоритеты задачи от низшего к высшему.
+var Priorities = []string{PriorityLow, PriorityMedium, PriorityHigh}
+
+// Возможные правила повторения задачи.
+const (
+	RecurrenceDaily   = "daily"
+	RecurrenceWeekly  = "weekly"
+	RecurrenceMonthly = "monthly"
+)
+
+// Recurrences перечисляет все допустимые правила повторения задачи.
+var Recurrences = []string{RecurrenceDaily, RecurrenceWeekly, RecurrenceMonthly}
+
 type Task struct {
-	ID          int    `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	DueDate     string `json:"due_date"`
-	CreatedAt   string `json:"created_at"`
-	UpdatedAt   string `json:"updated_at"`
+	ID            int             `json:"id" xml:"id"`
+	Title         string          `json:"title" xml:"title"`
+	Description   string          `json:"description" xml:"description"`
+	Status        string          `json:"status" xml:"status"`
+	Priority      string          `json:"priority" xml:"priority"`
+	Blocked       bool            `json:"blocked" xml:"blocked"`
+	BlockedReason string          `json:"blocked_reason" xml:"blocked_reason"`
+	DueDate       string          `json:"due_date" xml:"due_date"`
+	Metadata      json.RawMessage `json:"metadata" xml:"metadata"`
+	DependsOn     []int           `json:"depends_on,omitempty" xml:"depends_on>id,omitempty"`
+	Position      float64         `json:"position" xml:"position"`
+	CreatedAt     string          `json:"created_at" xml:"created_at"`
+	UpdatedAt     string          `json:"updated_at" xml:"updated_at"`
+	CompletedAt   string          `json:"completed_at" xml:"completed_at"`
+	DeletedAt     string          `json:"deleted_at,omitempty" xml:"deleted_at,omitempty"`
+	// Tags - метки задачи. При обновлении отсутствующее поле сохраняет метки,
+	// пустой список удаляет их.
+	Tags []string `json:"tags,omitempty" xml:"tags>tag,omitempty"`
+	// Recurrence задает повторение задачи: после выполнения создается следующая
+	// задача со сроком, сдвинутым на RecurrenceInterval дней, недель или месяцев.
+	Recurrence         string `json:"recurrence,omitempty" xml:"recurrence,omitempty"`
+	RecurrenceInterval int    `json:"recurrence_interval,omitempty" xml:"recurrence_interval,omitempty"`
+	// RecurredFrom - ID выполненной задачи, повторением которой является эта задача.
+	RecurredFrom int `json:"recurred_from,omitempty" xml:"recurred_from,omitempty"`
+	// RemindAt - время напоминания о задаче; Reminded - отправлено ли оно.
+	// Изменение времени напоминания сбрасывает Reminded.
+	RemindAt string `json:"remind_at" xml:"remind_at"`
+	Reminded bool   `json:"reminded" xml:"reminded"`
+	// ParentID - ID родительской задачи, если задача является подзадачей.
+	ParentID int `json:"parent_id,omitempty" xml:"parent_id,omitempty"`
+	// Version - версия задачи, увеличивается при каждом изменении. Передается
+	// клиентам в заголовке ETag; значение из тела запроса не используется.
+	Version int `json:"version" xml:"version"`
+	// SubtaskCount - количество неудаленных прямых подзадач; заполняется только
+	// в списке задач по запросу include=subtask_count.
+	SubtaskCount *int `json:"subtask_count,omitempty" xml:"subtask_count,omitempty"`
+	// Subtasks - подзадачи; заполняется только по запросу include=subtasks.
+	Subtasks []Task `json:"subtasks,omitempty" xml:"subtasks>task,omitempty"`
+	// UserID - владелец задачи; задается по аутентифицированному пользователю
+	// и не принимается от клиента.
+	UserID int `json:"-" xml:"-"`
+}
+
+// IsValidStatus сообщает, является ли status одним из допустимых статусов задачи.
+func IsValidStatus(status string) bool {
+	for _, s := range Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidationErrors содержит сообщения об ошибках проверки задачи по именам полей.
+type ValidationErrors map[string]string
+
+func (e ValidationErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field + ": " + e[field]
+	}
+	return strings.Join(messages, "; ")
+}
+
+// MarshalXML записывает ошибки в XML элементами с именами полей
+// в порядке имен, так же как в JSON.
+func (e ValidationErrors) MarshalXML(enc *xml.Encoder, start xml.StartElement) error {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, field := range fields {
+		if err := enc.EncodeElement(e[field], xml.StartElement{Name: xml.Name{Local: field}}); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// Validate проверяет поля задачи перед сохранением: заголовок и описание
+// должны быть заполнены, заголовок - не длиннее MaxTitleLength символов,
+// срок выполнения, если задан, - в формате RFC3339. Также проверяются статус,
+// приоритет, метаданные, повторение, время напоминания и метки. Возвращает ValidationErrors со всеми найденными ошибками или nil.
+func (t *Task) Validate() error {
+	errs := ValidationErrors{}
+	for _, field := range t.MissingFields() {
+		errs[field] = "must not be empty"
+	}
+	if len([]rune(t.Title)) > MaxTitleLength {
+		errs["title"] = fmt.Sprintf("must be at most %d characters", MaxTitleLength)
+	}
+	if t.DueDate != "" {
+		if _, err := time.Parse(time.RFC3339, t.DueDate); err != nil {
+			errs["due_date"] = "must be an RFC3339 timestamp, e.g. 2025-01-15T23:59:59Z"
+		}
+	}
+	if err := t.ValidateStatus(); err != nil {
+		errs["status"] = err.Error()
+	}
+	if err := t.ValidatePriority(); err != nil {
+		errs["priority"] = err.Error()
+	}
+	if err := t.ValidateMetadata(); err != nil {
+		errs["metadata"] = err.Error()
+	}
+	if err := t.ValidateRecurrence(); err != nil {
+		errs["recurrence"] = err.Error()
+	}
+	if err := t.ValidateRemindAt(); err != nil {
+		errs["remind_at"] = err.Error()
+	}
+	if err := t.ValidateTags(); err != nil {
+		errs["tags"] = err.Error()
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// MissingFields возвращает имена обязательных полей задачи (title, description),
+// которые не заполнены или состоят только из пробелов.
+func (t *Task) MissingFields() []string {
+	var missing []string
+	if strings.TrimSpace(t.Title) == "" {
+		missing = append(missing, "title")
+	}
+	if strings.TrimSpace(t.Description) == "" {
+		missing = append(missing, "description")
+	}
+	return missing
+}
+
+// ValidateStatus проверяет статус задачи. Пустой статус допустим:
+// обработчики заменяют его значением по умолчанию.
+func (t *Task) ValidateStatus() error {
+	if t.Status != "" && !IsValidStatus(t.Status) {
+		return errors.New("status must be one of: pending, in_progress, done")
+	}
+	return nil
+}
+
+// IsValidPriority сообщает, является ли priority одним из допустимых приоритетов задачи.
+func IsValidPriority(priority string) bool {
+	for _, p := range Priorities {
+		if p == priority {
+			return true
+		}
+	}
+	return false
+}
+
+// PriorityAtLeast сообщает, не ниже ли приоритет priority приоритета threshold
+// в порядке Priorities. Для неизвестных приоритетов возвращает false.
+func PriorityAtLeast(priority, threshold string) bool {
+	rank, minRank := slices.Index(Priorities, priority), slices.Index(Priorities, threshold)
+	return rank >= 0 && minRank >= 0 && rank >= minRank
+}
+
+// ValidatePriority проверяет приоритет задачи. Пустой приоритет допустим:
+// обработчики заменяют его значением по умолчанию.
+func (t *Task) ValidatePriority() error {
+	if t.Priority != "" && !IsValidPriority(t.Priority) {
+		return errors.New("priority must be one of: low, medium, high")
+	}
+	return nil
+}
+
+// ValidateMetadata проверяет, что произвольные метаданные задачи,
+// если они заданы, являются корректным JSON-объектом (или null).
+func (t *Task) ValidateMetadata() error {
+	if len(t.Metadata) == 0 {
+		return nil
+	}
+	if !json.Valid(t.Metadata) {
+		return errors.New("metadata must be valid JSON")
+	}
+	trimmed := bytes.TrimSpace(t.Metadata)
+	if bytes.Equal(trimmed, []byte("null")) {
+		// Явный null равнозначен отсутствию метаданных
+		t.Metadata = nil
+		return nil
+	}
+	if trimmed[0] != '{' {
+		return errors.New("metadata must be a JSON object")
+	}
+	return nil
+}
+
+// IsValidRecurrence сообщает, является ли recurrence одним из допустимых правил повторения.
+func IsValidRecurrence(recurrence string) bool {
+	for _, r := range Recurrences {
+		if r == recurrence {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRecurrence проверяет правило и интервал повторения задачи.
+// Пустое правило означает, что задача не повторяется; интервал без правила недопустим.
+// Для повторяющейся задачи без интервала устанавливается интервал 1.
+func (t *Task) ValidateRecurrence() error {
+	if t.Recurrence == "" {
+		if t.RecurrenceInterval != 0 {
+			return errors.New("recurrence_interval requires recurrence")
+		}
+		return nil
+	}
+	if !IsValidRecurrence(t.Recurrence) {
+		return errors.New("recurrence must be one of: daily, weekly, monthly")
+	}
+	if t.RecurrenceInterval < 0 {
+		return errors.New("recurrence_interval must be a positive integer")
+	}
+	if t.RecurrenceInterval == 0 {
+		t.RecurrenceInterval = 1
+	}
+	return nil
+}
+
+// ValidateRemindAt проверяет, что время напоминания, если задано, указано в формате RFC3339.
+func (t *Task) ValidateRemindAt() error {
+	if t.RemindAt == "" {
+		return nil
+	}
+	if _, err := time.Parse(time.RFC3339, t.RemindAt); err != nil {
+		return errors.New("remind_at must be an RFC3339 timestamp, e.g. 2025-01-15T09:00:00Z")
+	}
+	return nil
+}
+
+// NormalizeTag приводит метку к каноническому виду: без пробелов по краям
+// и в нижнем регистре, чтобы "Work" и "work " считались одной меткой.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// ValidateTags приводит метки задачи к каноническому виду, удаляет повторы
+// и упорядочивает их по имени. Пустые метки и метки длиннее MaxTagLength символов
+// недопустимы. nil сохраняется как nil, чтобы отличать отсутствующее поле от пустого списка.
+func (t *Task) ValidateTags() error {
+	if t.Tags == nil {
+		return nil
+	}
+	seen := make(map[string]bool, len(t.Tags))
+	tags := []string{}
+	for _, tag := range t.Tags {
+		tag = NormalizeTag(tag)
+		if tag == "" {
+			return errors.New("tags must not be empty")
+		}
+		if len([]rune(tag)) > MaxTagLength {
+			return fmt.Errorf("tags must be at most %d characters", MaxTagLength)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	t.Tags = tags
+	return nil
 }
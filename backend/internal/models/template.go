@@ -0,0 +1,44 @@
+package models
+
+import "errors"
+
+// TaskTemplate описывает шаблон, из которого создаются типовые задачи.
+// DueInDays задает срок выполнения создаваемой задачи в днях от момента создания;
+// nil означает задачу без срока. Priority и Tags копируются в создаваемую задачу.
+type TaskTemplate struct {
+	ID          int      `json:"id" xml:"id"`
+	Name        string   `json:"name" xml:"name"`
+	Title       string   `json:"title" xml:"title"`
+	Description string   `json:"description" xml:"description"`
+	DueInDays   *int     `json:"due_in_days" xml:"due_in_days"`
+	Priority    string   `json:"priority" xml:"priority"`
+	Tags        []string `json:"tags" xml:"tags>tag"`
+	CreatedAt   string   `json:"created_at" xml:"created_at"`
+}
+
+// Validate проверяет обязательные поля шаблона, корректность смещения срока
+// и приоритета. Метки проверяются и приводятся к каноническому виду так же,
+// как метки задачи.
+func (t *TaskTemplate) Validate() error {
+	if t.Name == "" {
+		return errors.New("name is required")
+	}
+	if t.Title == "" {
+		return errors.New("title is required")
+	}
+	if len([]rune(t.Title)) > MaxTitleLength {
+		return errors.New("title is too long")
+	}
+	if t.DueInDays != nil && *t.DueInDays < 0 {
+		return errors.New("due_in_days must not be negative")
+	}
+	task := Task{Priority: t.Priority, Tags: t.Tags}
+	if err := task.ValidatePriority(); err != nil {
+		return err
+	}
+	if err := task.ValidateTags(); err != nil {
+		return err
+	}
+	t.Tags = task.Tags
+	return nil
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MinPasswordLength - минимальная длина пароля пользователя в символах.
+const MinPasswordLength = 8
+
+// MaxPasswordBytes - максимальная длина пароля в байтах: bcrypt не учитывает
+// байты после 72-го, поэтому более длинные пароли отклоняются.
+const MaxPasswordBytes = 72
+
+// User описывает учетную запись пользователя. Хеш пароля никогда не
+// возвращается клиентам.
+type User struct {
+	ID           int    `json:"id" xml:"id"`
+	Email        string `json:"email" xml:"email"`
+	PasswordHash string `json:"-" xml:"-"`
+	CreatedAt    string `json:"created_at" xml:"created_at"`
+}
+
+// Credentials описывает данные для регистрации пользователя.
+type Credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// Validate проверяет адрес электронной почты и длину пароля.
+// Адрес приводится к нижнему регистру, чтобы не различать учетные записи по регистру.
+func (c *Credentials) Validate() error {
+	c.Email = strings.ToLower(strings.TrimSpace(c.Email))
+	if c.Email == "" || !strings.Contains(c.Email, "@") {
+		return errors.New("email must be a valid email address")
+	}
+	if len([]rune(c.Password)) < MinPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	if len(c.Password) > MaxPasswordBytes {
+		return fmt.Errorf("password must be at most %d bytes", MaxPasswordBytes)
+	}
+	return nil
+}
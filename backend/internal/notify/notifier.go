@@ -0,0 +1,18 @@
+package notify
+
+import "context"
+
+// Reminder описывает напоминание о задаче, передаваемое получателю уведомлений.
+type Reminder struct {
+	TaskID   int    `json:"task_id"`
+	UserID   int    `json:"user_id"`
+	Title    string `json:"title"`
+	DueDate  string `json:"due_date,omitempty"`
+	RemindAt string `json:"remind_at"`
+}
+
+// Notifier доставляет напоминания о задачах. Реализация должна вернуть ошибку,
+// если напоминание не доставлено: тогда оно будет отправлено повторно.
+type Notifier interface {
+	Notify(ctx context.Context, reminder Reminder) error
+}
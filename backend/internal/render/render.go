@@ -0,0 +1,113 @@
+// Package render записывает ответы HTTP в формате, который клиент предпочитает
+// по заголовку Accept: в JSON по умолчанию или в XML (encoding/xml) для клиентов,
+// запросивших application/xml или text/xml. Имена элементов XML задаются тегами xml
+// типов ответов и совпадают с именами полей JSON.
+package render
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// xmlRootElement - корневой элемент ответа в формате XML.
+const xmlRootElement = "response"
+
+// xmlList - ответ-список в формате XML: каждое значение - элемент item
+// внутри корневого элемента.
+type xmlList struct {
+	XMLName xml.Name    `xml:"response"`
+	Items   interface{} `xml:"item"`
+}
+
+// xmlError - сообщение об ошибке в формате XML.
+type xmlError struct {
+	XMLName xml.Name `xml:"error"`
+	Message string   `xml:",chardata"`
+}
+
+// PrefersXML сообщает, предпочитает ли клиент по заголовку Accept запроса r формат XML
+// формату JSON. При равных весах, без заголовка Accept или с application/json выбирается JSON.
+func PrefersXML(r *http.Request) bool {
+	jsonQ, xmlQ := 0.0, 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case "application/json":
+			jsonQ = max(jsonQ, q)
+		case "application/xml", "text/xml":
+			xmlQ = max(xmlQ, q)
+		}
+	}
+	return xmlQ > jsonQ
+}
+
+// Write записывает v с кодом status в формате, выбранном по запросу r. Заголовки
+// Content-Type и Vary задаются до отправки кода ответа. В XML значение записывается
+// в корневой элемент response, а список - элементами item внутри него.
+// Возвращает ошибку кодирования или записи тела ответа.
+func Write(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	// Формат ответа зависит от заголовка Accept, что важно для кэшей
+	w.Header().Add("Vary", "Accept")
+	if !PrefersXML(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		return json.NewEncoder(w).Encode(v)
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	if kind := reflect.ValueOf(v).Kind(); kind == reflect.Slice || kind == reflect.Array {
+		return writeXML(w, xmlList{Items: v}, nil)
+	}
+	return writeXML(w, v, &xml.StartElement{Name: xml.Name{Local: xmlRootElement}})
+}
+
+// Error отвечает клиенту сообщением об ошибке message с кодом status: текстом,
+// как http.Error, или элементом error, если клиент предпочитает XML.
+func Error(w http.ResponseWriter, r *http.Request, message string, status int) {
+	w.Header().Add("Vary", "Accept")
+	if !PrefersXML(r) {
+		http.Error(w, message, status)
+		return
+	}
+	// Как и http.Error, не даем браузеру угадывать тип содержимого ответа об ошибке
+	w.Header().Del("Content-Length")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	writeXML(w, xmlError{Message: message}, nil)
+}
+
+// writeXML записывает v документом XML с объявлением. Если start задан,
+// он заменяет корневой элемент значения.
+func writeXML(w http.ResponseWriter, v interface{}, start *xml.StartElement) error {
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	var err error
+	if start != nil {
+		err = enc.EncodeElement(v, *start)
+	} else {
+		err = enc.Encode(v)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write([]byte("\n"))
+	return err
+}
@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/database"
+	"github.com/NickolaiP/taskApi/backend/internal/logger"
+	"github.com/NickolaiP/taskApi/backend/internal/models"
+)
+
+// generateOccurrencesQuery создает следующее повторение для каждой выполненной
+// повторяющейся задачи, у которой его еще нет. Срок повторения сдвигается
+// от срока задачи (или от времени выполнения, если срока нет) на интервал
+// повторения; повторение подзадачи остается подзадачей того же родителя. Уникальный recurred_from делает запрос идемпотентным: повторный
+// запуск, в том числе после перезапуска сервиса или на нескольких экземплярах
+// одновременно, не создает дубликатов. Пока у пользователя есть другая открытая задача
+// с тем же заголовком (см. 0023_task_open_title_unique.sql), повторение не создается:
+// запрос пропускает его и создаст при следующем запуске после того, как заголовок
+// освободится. Создание каждого повторения записывается
+// в журнал изменений без пользователя: его выполняет сам сервис.
+const generateOccurrencesQuery = `WITH created AS (INSERT INTO tasks (title, description, status, priority, due_date, metadata,
+		created_at, updated_at, user_id, recurrence, recurrence_interval, recurred_from, parent_id)
+	SELECT t.title, t.description, 'pending', t.priority,
+		COALESCE(t.due_date, t.completed_at) + CASE t.recurrence
+			WHEN 'daily' THEN make_interval(days => t.recurrence_interval)
+			WHEN 'weekly' THEN make_interval(weeks => t.recurrence_interval)
+			ELSE make_interval(months => t.recurrence_interval)
+		END,
+		t.metadata, $1, $1, t.user_id, t.recurrence, t.recurrence_interval, t.id, t.parent_id
+	FROM tasks t
+	WHERE t.status = 'done' AND t.recurrence IS NOT NULL AND t.deleted_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM tasks n WHERE n.recurred_from = t.id)
+	ON CONFLICT DO NOTHING
+	RETURNING id)
+INSERT INTO task_audit (task_id, action, created_at) SELECT id, '` + models.AuditCreated + `', $1 FROM created`
+
+// RecurrenceWorker периодически создает следующие повторения выполненных
+// повторяющихся задач.
+type RecurrenceWorker struct {
+	db       database.Database
+	logger   *logger.Logger
+	interval time.Duration
+}
+
+// NewRecurrenceWorker создает RecurrenceWorker, который проверяет задачи
+// с периодичностью interval.
+func NewRecurrenceWorker(db database.Database, logger *logger.Logger, interval time.Duration) *RecurrenceWorker {
+	return &RecurrenceWorker{
+		db:       db,
+		logger:   logger,
+		interval: interval,
+	}
+}
+
+// Run создает повторения сразу и затем каждые interval, пока не будет отменен ctx.
+func (w *RecurrenceWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.generate(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// generate создает недостающие повторения задач и записывает в лог их количество.
+func (w *RecurrenceWorker) generate(ctx context.Context) {
+	// Создаем контекст с таймаутом для операции с базой данных. Запрос не отменяется
+	// вместе с ctx: при остановке сервиса начатый проход завершается
+	dbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+
+	result, err := w.db.Exec(dbCtx, generateOccurrencesQuery, time.Now().Format(time.RFC3339))
+	if err != nil {
+		w.logger.Error("Failed to generate recurring tasks", "error", err)
+		return
+	}
+	if created, err := result.RowsAffected(); err == nil && created > 0 {
+		w.logger.Info("Recurring tasks generated", "count", created)
+	}
+}
@@ -0,0 +1,122 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/NickolaiP/taskApi/backend/internal/database"
+	"github.com/NickolaiP/taskApi/backend/internal/logger"
+	"github.com/NickolaiP/taskApi/backend/internal/notify"
+)
+
+const (
+	// reminderBatchSize ограничивает количество напоминаний, отправляемых за один проход.
+	reminderBatchSize = 100
+	// reminderBatchTimeout ограничивает время одного прохода, включая доставку напоминаний.
+	reminderBatchTimeout = 5 * time.Minute
+)
+
+// ReminderWorker периодически отправляет напоминания о задачах, время напоминания
+// которых наступило, и отмечает их отправленными.
+type ReminderWorker struct {
+	db       database.Database
+	logger   *logger.Logger
+	notifier notify.Notifier
+	interval time.Duration
+}
+
+// NewReminderWorker создает ReminderWorker, который проверяет напоминания
+// с периодичностью interval и доставляет их через notifier.
+func NewReminderWorker(db database.Database, logger *logger.Logger, notifier notify.Notifier, interval time.Duration) *ReminderWorker {
+	return &ReminderWorker{
+		db:       db,
+		logger:   logger,
+		notifier: notifier,
+		interval: interval,
+	}
+}
+
+// Run отправляет напоминания сразу и затем каждые interval, пока не будет отменен ctx.
+func (w *ReminderWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if err := w.sendDue(ctx); err != nil && ctx.Err() == nil {
+			w.logger.Error("Failed to send reminders", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDue отправляет наступившие напоминания о невыполненных задачах.
+// Напоминания выбираются с блокировкой строк (SKIP LOCKED), поэтому несколько
+// экземпляров сервиса не отправят одно напоминание дважды. Отправленные
+// напоминания отмечаются в той же транзакции; недоставленные остаются
+// неотмеченными и отправляются при следующем проходе.
+func (w *ReminderWorker) sendDue(ctx context.Context) error {
+	// Транзакция не отменяется вместе с ctx: при остановке сервиса уже
+	// отправленные напоминания должны быть отмечены, иначе они уйдут повторно
+	dbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), reminderBatchTimeout)
+	defer cancel()
+
+	tx, err := w.db.BeginTx(dbCtx)
+	if err != nil {
+		return err
+	}
+	// После Commit откат ничего не делает
+	defer tx.Rollback()
+
+	query := `SELECT id, user_id, title, due_date, remind_at FROM tasks
+		WHERE remind_at <= $1 AND NOT reminded AND status <> 'done' AND deleted_at IS NULL
+		ORDER BY remind_at LIMIT $2 FOR UPDATE SKIP LOCKED`
+	rows, err := tx.Query(dbCtx, query, time.Now().UTC(), reminderBatchSize)
+	if err != nil {
+		return err
+	}
+	var reminders []notify.Reminder
+	for rows.Next() {
+		var reminder notify.Reminder
+		var userID sql.NullInt64
+		var dueDate sql.NullString
+		if err := rows.Scan(&reminder.TaskID, &userID, &reminder.Title, &dueDate, &reminder.RemindAt); err != nil {
+			rows.Close()
+			return err
+		}
+		reminder.UserID = int(userID.Int64)
+		reminder.DueDate = dueDate.String
+		reminders = append(reminders, reminder)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Доставка прекращается при остановке сервиса; оставшиеся напоминания
+	// будут отправлены после перезапуска
+	sent := 0
+	for _, reminder := range reminders {
+		if ctx.Err() != nil {
+			break
+		}
+		if err := w.notifier.Notify(ctx, reminder); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			w.logger.Warn("Failed to deliver reminder", "task_id", reminder.TaskID, "error", err)
+			continue
+		}
+		if _, err := tx.Exec(dbCtx, "UPDATE tasks SET reminded=true WHERE id=$1", reminder.TaskID); err != nil {
+			return err
+		}
+		sent++
+	}
+	if sent > 0 {
+		w.logger.Info("Reminders sent", "count", sent)
+	}
+	return tx.Commit()
+}